@@ -0,0 +1,246 @@
+// builder.go: fluent flag definition builder
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGILira library
+// SPDX-License-Identifier: MPL-2.0
+
+package flashflags
+
+import (
+	"fmt"
+	"time"
+)
+
+// FlagBuilder defines a flag step by step using a fluent API.
+// It is an ergonomic layer over the FlagSet definition methods (StringVar, SetRequired,
+// SetGroup, SetIntRange, ...) and applies them all when Build is called.
+//
+// Example:
+//
+//	flag, err := fs.NewFlag("port").
+//		Short("p").
+//		Int(8080).
+//		Usage("Server port").
+//		Required().
+//		Group("Server").
+//		Range(1, 65535).
+//		Build()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+type FlagBuilder struct {
+	fs           *FlagSet
+	name         string
+	shortKey     string
+	usage        string
+	flagType     string
+	defaultValue interface{}
+	required     bool
+	group        string
+	envVar       string
+	dependencies []string
+	validator    func(interface{}) error
+	hasRange     bool
+	rangeMin     int
+	rangeMax     int
+}
+
+// NewFlag starts building a flag with the given long name.
+// A type method (String, Int, Bool, Float64, Duration, StringSlice) must be called before Build.
+func (fs *FlagSet) NewFlag(name string) *FlagBuilder {
+	return &FlagBuilder{fs: fs, name: name}
+}
+
+// Short sets the single-character short key (e.g., "p" for -p).
+func (b *FlagBuilder) Short(shortKey string) *FlagBuilder {
+	b.shortKey = shortKey
+	return b
+}
+
+// Usage sets the help description of the flag.
+func (b *FlagBuilder) Usage(usage string) *FlagBuilder {
+	b.usage = usage
+	return b
+}
+
+// String makes the flag a string flag with the given default value.
+func (b *FlagBuilder) String(defaultValue string) *FlagBuilder {
+	b.flagType, b.defaultValue = "string", defaultValue
+	return b
+}
+
+// Int makes the flag an int flag with the given default value.
+func (b *FlagBuilder) Int(defaultValue int) *FlagBuilder {
+	b.flagType, b.defaultValue = "int", defaultValue
+	return b
+}
+
+// Bool makes the flag a bool flag with the given default value.
+func (b *FlagBuilder) Bool(defaultValue bool) *FlagBuilder {
+	b.flagType, b.defaultValue = "bool", defaultValue
+	return b
+}
+
+// Float64 makes the flag a float64 flag with the given default value.
+func (b *FlagBuilder) Float64(defaultValue float64) *FlagBuilder {
+	b.flagType, b.defaultValue = "float64", defaultValue
+	return b
+}
+
+// Duration makes the flag a duration flag with the given default value.
+func (b *FlagBuilder) Duration(defaultValue time.Duration) *FlagBuilder {
+	b.flagType, b.defaultValue = "duration", defaultValue
+	return b
+}
+
+// StringSlice makes the flag a string slice flag with the given default value.
+func (b *FlagBuilder) StringSlice(defaultValue []string) *FlagBuilder {
+	b.flagType, b.defaultValue = "stringSlice", defaultValue
+	return b
+}
+
+// Required marks the flag as required (see FlagSet.SetRequired).
+func (b *FlagBuilder) Required() *FlagBuilder {
+	b.required = true
+	return b
+}
+
+// Group sets the help group of the flag (see FlagSet.SetGroup).
+func (b *FlagBuilder) Group(group string) *FlagBuilder {
+	b.group = group
+	return b
+}
+
+// Env sets a custom environment variable name for the flag (see FlagSet.SetEnvVar).
+func (b *FlagBuilder) Env(envVar string) *FlagBuilder {
+	b.envVar = envVar
+	return b
+}
+
+// DependsOn sets the flags this flag depends on (see FlagSet.SetDependencies).
+func (b *FlagBuilder) DependsOn(dependencies ...string) *FlagBuilder {
+	b.dependencies = dependencies
+	return b
+}
+
+// Validator sets a custom validation function (see FlagSet.SetValidator).
+func (b *FlagBuilder) Validator(validator func(interface{}) error) *FlagBuilder {
+	b.validator = validator
+	return b
+}
+
+// Range constrains an int flag to the inclusive range [min, max] (see FlagSet.SetIntRange).
+func (b *FlagBuilder) Range(min, max int) *FlagBuilder {
+	b.hasRange = true
+	b.rangeMin = min
+	b.rangeMax = max
+	return b
+}
+
+// Build registers the flag in the FlagSet and applies all configured attributes.
+// Returns the registered Flag, or an error if no type was chosen or an attribute is invalid.
+// A failed Build leaves the FlagSet as it was.
+func (b *FlagBuilder) Build() (*Flag, error) {
+	if b.name == "" {
+		return nil, fmt.Errorf("flag name cannot be empty")
+	}
+
+	fs := b.fs
+	fs.lock()
+	prevFlag, prevShort, prevRedefined := fs.flags[b.name], fs.shortMap[b.shortKey], len(fs.redefined)
+	fs.unlock()
+
+	if err := b.register(); err != nil {
+		return nil, err
+	}
+
+	flag, err := b.configure()
+	if err != nil {
+		b.unregister(prevFlag, prevShort, prevRedefined)
+		return nil, err
+	}
+	return flag, nil
+}
+
+// configure applies the builder's attributes to the registered flag
+func (b *FlagBuilder) configure() (*Flag, error) {
+	fs := b.fs
+	if b.required {
+		if err := fs.SetRequired(b.name); err != nil {
+			return nil, err
+		}
+	}
+	if b.group != "" {
+		if err := fs.SetGroup(b.name, b.group); err != nil {
+			return nil, err
+		}
+	}
+	if b.envVar != "" {
+		if err := fs.SetEnvVar(b.name, b.envVar); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.dependencies) > 0 {
+		if err := fs.SetDependencies(b.name, b.dependencies...); err != nil {
+			return nil, err
+		}
+	}
+	if b.validator != nil {
+		if err := fs.SetValidator(b.name, b.validator); err != nil {
+			return nil, err
+		}
+	}
+	if b.hasRange {
+		if err := fs.SetIntRange(b.name, b.rangeMin, b.rangeMax); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs.Lookup(b.name), nil
+}
+
+// unregister restores the flag and short key that a failed Build replaced
+func (b *FlagBuilder) unregister(prevFlag, prevShort *Flag, prevRedefined int) {
+	fs := b.fs
+	fs.lock()
+	defer fs.unlock()
+
+	if prevFlag != nil {
+		fs.flags[b.name] = prevFlag
+	} else {
+		delete(fs.flags, b.name)
+	}
+	if b.shortKey != "" {
+		if prevShort != nil {
+			fs.registerShortKey(b.shortKey, prevShort)
+		} else {
+			delete(fs.shortMap, b.shortKey)
+			if len(b.shortKey) == 1 && b.shortKey[0] < 128 {
+				fs.shortASCII[b.shortKey[0]] = nil
+			}
+		}
+	}
+	fs.redefined = fs.redefined[:prevRedefined]
+}
+
+// register defines the flag using the type-specific FlagSet method
+func (b *FlagBuilder) register() error {
+	fs := b.fs
+	switch b.flagType {
+	case "string":
+		fs.StringVar(b.name, b.shortKey, b.defaultValue.(string), b.usage)
+	case "int":
+		fs.IntVar(b.name, b.shortKey, b.defaultValue.(int), b.usage)
+	case "bool":
+		fs.BoolVar(b.name, b.shortKey, b.defaultValue.(bool), b.usage)
+	case "float64":
+		fs.Float64Var(b.name, b.shortKey, b.defaultValue.(float64), b.usage)
+	case "duration":
+		fs.DurationVar(b.name, b.shortKey, b.defaultValue.(time.Duration), b.usage)
+	case "stringSlice":
+		fs.StringSliceVar(b.name, b.shortKey, b.defaultValue.([]string), b.usage)
+	default:
+		return fmt.Errorf("flag --%s has no type: call String, Int, Bool, Float64, Duration or StringSlice before Build", b.name)
+	}
+	return nil
+}
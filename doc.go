@@ -54,11 +54,15 @@
 //	  -abc                  (equivalent to -a -b -c)
 //	  -abc value            (with value for last flag)
 //	  -vdp 8080             (verbose + debug + port=8080)
+//	  -d- / -d+             (explicit boolean false / true, also within -vd-)
 //
 //	Special syntax:
 //	  --help, -h            (shows help)
 //	  --                    (end of flags marker)
 //
+// An empty value (--flag=) clears string, slice, and map flags to "", [] and an empty map.
+// Numeric, duration, and boolean flags need a value and reject it with an error.
+//
 // All boolean flags except the last in combined sequences (-abc) must be boolean.
 // The last flag in a combined sequence can be any type and will consume the next argument as its value.
 //
@@ -303,7 +307,8 @@
 //   - Dependency errors: "flag --tls-cert requires --enable-tls to be set"
 //   - Type conversion errors: "invalid int value for flag --port: abc"
 //   - Configuration errors: "config file error: failed to read config.json"
-//   - Help requests: "help requested" (special case, not a real error)
+//   - Help requests: ErrHelp, "help requested" (special case, not a real error)
+//   - Version requests: ErrVersion, "version requested" (special case, not a real error)
 //   - Security validation errors: "flag --name contains dangerous pattern"
 //   - Buffer overflow errors: "flag --data value too long: 15000 chars (max: 10000)"
 //
@@ -0,0 +1,37 @@
+//go:build windows
+
+// env_separator_windows_test.go: OS path list separator tests for Windows
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGILira library
+// SPDX-License-Identifier: MPL-2.0
+
+package flashflags
+
+import (
+	"os"
+	"testing"
+)
+
+// TestEnvOSPathSeparatorWindows tests that string slices split on ';' on Windows
+func TestEnvOSPathSeparatorWindows(t *testing.T) {
+	fs := New("test")
+	paths := fs.StringSlice("plugin-path", nil, "Plugin search path")
+	fs.SetEnvPrefix("SEPTEST")
+	if err := fs.SetEnvOSPathSeparator("plugin-path"); err != nil {
+		t.Fatalf("SetEnvOSPathSeparator failed: %v", err)
+	}
+	if fs.Lookup("plugin-path").envSeparator != ";" {
+		t.Errorf("Expected ';' separator, got %q", fs.Lookup("plugin-path").envSeparator)
+	}
+
+	_ = os.Setenv("SEPTEST_PLUGIN_PATH", "plugins;more-plugins")
+	defer func() { _ = os.Unsetenv("SEPTEST_PLUGIN_PATH") }()
+
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(*paths) != 2 || (*paths)[0] != "plugins" || (*paths)[1] != "more-plugins" {
+		t.Errorf("Expected [plugins more-plugins], got %v", *paths)
+	}
+}
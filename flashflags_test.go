@@ -7,11 +7,21 @@
 package flashflags
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
+	"unicode/utf8"
 )
 
 // Test basic flag definition and parsing
@@ -258,8 +268,7 @@ func TestShortFlagEquals(t *testing.T) {
 
 	t.Run("duration short flag with equals", func(t *testing.T) {
 		fs := New("test")
-		timeout := fs.Duration("timeout", 30*time.Second, "Timeout duration")
-		fs.shortMap["t"] = fs.flags["timeout"] // Add short key
+		timeout := fs.DurationVar("timeout", "t", 30*time.Second, "Timeout duration")
 
 		args := []string{"-t=45s"}
 		err := fs.Parse(args)
@@ -412,8 +421,8 @@ func TestCombinedShortFlags(t *testing.T) {
 		if err == nil {
 			t.Error("Expected error for unknown flag in combined sequence")
 		}
-		if !strings.Contains(err.Error(), "unknown flag in combined sequence") {
-			t.Errorf("Expected 'unknown flag in combined sequence' error, got: %v", err)
+		if !strings.Contains(err.Error(), "unknown flag: -x in combined sequence -vx") {
+			t.Errorf("Expected 'unknown flag: -x in combined sequence -vx' error, got: %v", err)
 		}
 	})
 
@@ -1018,6 +1027,43 @@ func TestFlagDependencies(t *testing.T) {
 		testNonExistentDependency(t)
 	})
 
+	t.Run("TwoNodeCycle", func(t *testing.T) {
+		fs := New("test")
+		fs.String("a", "", "A flag")
+		fs.String("b", "", "B flag")
+		_ = fs.SetDependencies("a", "b")
+		_ = fs.SetDependencies("b", "a")
+
+		err := fs.Parse([]string{"--a", "x", "--b", "y"})
+		verifyExpectedError(t, err, "dependency cycle: a → b → a", "Expected error for two-node cycle")
+	})
+
+	t.Run("ThreeNodeCycle", func(t *testing.T) {
+		fs := New("test")
+		fs.String("a", "", "A flag")
+		fs.String("b", "", "B flag")
+		fs.String("c", "", "C flag")
+		_ = fs.SetDependencies("a", "b")
+		_ = fs.SetDependencies("b", "c")
+		_ = fs.SetDependencies("c", "a")
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "dependency cycle: a → b → c → a", "Expected error for three-node cycle")
+	})
+
+	t.Run("ValidDAG", func(t *testing.T) {
+		fs := New("test")
+		fs.String("a", "", "A flag")
+		fs.String("b", "", "B flag")
+		fs.String("c", "", "C flag")
+		_ = fs.SetDependencies("a", "b", "c")
+		_ = fs.SetDependencies("b", "c")
+
+		if err := fs.Parse([]string{"--a", "x", "--b", "y", "--c", "z"}); err != nil {
+			t.Errorf("Expected no error for valid DAG, got %v", err)
+		}
+	})
+
 	t.Run("SetDependenciesNonExistentFlag", func(t *testing.T) {
 		fs := New("test")
 		err := fs.SetDependencies("nonexistent", "something")
@@ -2803,3 +2849,5755 @@ func TestArgsParsing(t *testing.T) {
 		}
 	})
 }
+
+// TestIntRangeClamping tests SetIntRange with and without SetClampToRange
+func TestIntRangeClamping(t *testing.T) {
+	t.Run("out of range errors by default", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 8080, "Port")
+		if err := fs.SetIntRange("port", 1, 65535); err != nil {
+			t.Fatalf("SetIntRange failed: %v", err)
+		}
+
+		err := fs.Parse([]string{"--port", "70000"})
+		verifyExpectedError(t, err, "flag --port value 70000 out of range [1, 65535]", "Expected out of range error")
+	})
+
+	t.Run("env value above max is clamped with warning", func(t *testing.T) {
+		fs := New("test")
+		workers := fs.Int("workers", 4, "Number of workers")
+		_ = fs.SetIntRange("workers", 1, 16)
+		_ = fs.SetClampToRange("workers")
+		fs.SetEnvPrefix("CLAMPTEST")
+
+		_ = os.Setenv("CLAMPTEST_WORKERS", "64")
+		defer func() { _ = os.Unsetenv("CLAMPTEST_WORKERS") }()
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Expected clamping instead of error, got %v", err)
+		}
+		if *workers != 16 || fs.GetInt("workers") != 16 {
+			t.Errorf("Expected workers clamped to 16, got %d", *workers)
+		}
+
+		warnings := fs.Warnings()
+		if len(warnings) != 1 {
+			t.Fatalf("Expected 1 warning, got %v", warnings)
+		}
+		if warnings[0] != "flag --workers value 64 clamped to 16 (range [1, 16])" {
+			t.Errorf("Unexpected warning: %s", warnings[0])
+		}
+	})
+
+	t.Run("value below min is clamped", func(t *testing.T) {
+		fs := New("test")
+		workers := fs.Int("workers", 4, "Number of workers")
+		_ = fs.SetIntRange("workers", 1, 16)
+		_ = fs.SetClampToRange("workers")
+
+		if err := fs.Parse([]string{"--workers=-3"}); err != nil {
+			t.Fatalf("Expected clamping instead of error, got %v", err)
+		}
+		if *workers != 1 {
+			t.Errorf("Expected workers clamped to 1, got %d", *workers)
+		}
+	})
+
+	t.Run("invalid setup", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "", "Host")
+		fs.Int("port", 0, "Port")
+
+		verifyExpectedError(t, fs.SetIntRange("host", 1, 2), "flag --host is not an int flag", "Expected type error")
+		verifyExpectedError(t, fs.SetIntRange("port", 5, 1), "invalid range for flag --port: min 5 > max 1", "Expected range error")
+		verifyExpectedError(t, fs.SetIntRange("missing", 1, 2), "flag not found: missing", "Expected not found error")
+		verifyExpectedError(t, fs.SetClampToRange("missing"), "flag not found: missing", "Expected not found error")
+	})
+}
+
+// TestArgsFromMap tests deterministic argument building from a map
+func TestArgsFromMap(t *testing.T) {
+	args := ArgsFromMap(map[string]string{
+		"port":  "8080",
+		"host":  "localhost",
+		"tags":  "a,b",
+		"debug": "true",
+		"name":  "",
+	})
+
+	expected := []string{"--debug=true", "--host=localhost", "--name=", "--port=8080", "--tags=a,b"}
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("Expected args[%d] = %q, got %q", i, expected[i], args[i])
+		}
+	}
+
+	// Built args must be accepted by Parse
+	fs := New("test")
+	host := fs.String("host", "", "Host")
+	port := fs.Int("port", 0, "Port")
+	debug := fs.Bool("debug", false, "Debug")
+	name := fs.String("name", "default", "Name")
+	tags := fs.StringSlice("tags", nil, "Tags")
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "localhost" || *port != 8080 || !*debug || *name != "" || len(*tags) != 2 {
+		t.Errorf("Unexpected parsed values: host=%s port=%d debug=%t name=%q tags=%v", *host, *port, *debug, *name, *tags)
+	}
+
+	if empty := ArgsFromMap(nil); len(empty) != 0 {
+		t.Errorf("Expected empty args for nil map, got %v", empty)
+	}
+}
+
+// TestFlagBuilder tests defining flags with the fluent builder
+func TestFlagBuilder(t *testing.T) {
+	t.Run("fully configured flag", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "", "Host")
+
+		flag, err := fs.NewFlag("port").
+			Short("p").
+			Int(8080).
+			Usage("Server port").
+			Required().
+			Group("Server").
+			Range(1, 65535).
+			DependsOn("host").
+			Env("BUILDER_PORT").
+			Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+
+		if flag.Name() != "port" || flag.ShortKey() != "p" || flag.Type() != "int" || flag.Usage() != "Server port" {
+			t.Errorf("Unexpected flag metadata: %s %s %s %s", flag.Name(), flag.ShortKey(), flag.Type(), flag.Usage())
+		}
+		if flag.Value() != 8080 {
+			t.Errorf("Expected default 8080, got %v", flag.Value())
+		}
+		if !flag.required || flag.group != "Server" || flag.envVar != "BUILDER_PORT" {
+			t.Errorf("Expected required, group and env var to be set")
+		}
+		if !flag.hasIntRange || flag.intMin != 1 || flag.intMax != 65535 {
+			t.Errorf("Expected range [1, 65535], got [%d, %d]", flag.intMin, flag.intMax)
+		}
+		if len(flag.dependencies) != 1 || flag.dependencies[0] != "host" {
+			t.Errorf("Expected dependency on host, got %v", flag.dependencies)
+		}
+
+		if err := fs.Parse([]string{"--host", "x", "-p", "3000"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if fs.GetInt("port") != 3000 {
+			t.Errorf("Expected port 3000, got %d", fs.GetInt("port"))
+		}
+	})
+
+	t.Run("short key for duration flags", func(t *testing.T) {
+		fs := New("test")
+		_, err := fs.NewFlag("timeout").Short("t").Duration(time.Second).Usage("Timeout").
+			Validator(func(v interface{}) error { return nil }).Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if err := fs.Parse([]string{"-t", "45s"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if fs.GetDuration("timeout") != 45*time.Second {
+			t.Errorf("Expected 45s, got %v", fs.GetDuration("timeout"))
+		}
+	})
+
+	t.Run("short key conflicts are detected", func(t *testing.T) {
+		fs := New("test")
+		fs.IntVar("retries", "r", 3, "Retries")
+		if _, err := fs.NewFlag("rate").Short("r").Float64(1).Build(); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		err := fs.CheckConsistency()
+		if err == nil || !strings.Contains(err.Error(), "short flag -r used by both --rate and --retries") {
+			t.Errorf("Expected short key conflict, got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs := New("test")
+		_, err := fs.NewFlag("name").Usage("No type").Build()
+		verifyExpectedError(t, err, "flag --name has no type: call String, Int, Bool, Float64, Duration or StringSlice before Build", "Expected missing type error")
+
+		_, err = fs.NewFlag("host").String("").Range(1, 2).Build()
+		verifyExpectedError(t, err, "flag --host is not an int flag", "Expected range type error")
+	})
+
+	t.Run("failed build leaves no flag behind", func(t *testing.T) {
+		fs := New("test")
+		fs.StringVar("mode", "m", "dev", "Mode")
+		_, err := fs.NewFlag("name").Short("n").String("x").Required().Range(1, 2).Build()
+		verifyExpectedError(t, err, "flag --name is not an int flag", "Expected range type error")
+		_, err = fs.NewFlag("mode").Short("m").Float64(1).Required().Range(1, 2).Build()
+		verifyExpectedError(t, err, "flag --mode is not an int flag", "Expected range type error")
+
+		if fs.Lookup("name") != nil || fs.shortMap["n"] != nil || fs.shortASCII['n'] != nil {
+			t.Error("Expected the failed flag to be removed")
+		}
+		if fs.Lookup("mode").Type() != "string" || fs.shortMap["m"] != fs.Lookup("mode") || len(fs.redefined) != 0 {
+			t.Error("Expected the replaced flag to be restored")
+		}
+		if err := fs.Parse([]string{"-m", "prod"}); err != nil || fs.GetString("mode") != "prod" {
+			t.Errorf("Expected parse to succeed, got %q (err: %v)", fs.GetString("mode"), err)
+		}
+	})
+}
+
+// TestRun tests parsing os.Args through Run and the help/version sentinels
+func TestRun(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	t.Run("parses os.Args", func(t *testing.T) {
+		fs := New("test")
+		port := fs.IntVar("port", "p", 8080, "Port")
+		os.Args = []string{"test", "-p", "3000", "file"}
+
+		if err := fs.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if *port != 3000 || fs.Arg(0) != "file" {
+			t.Errorf("Expected port 3000 and arg 'file', got %d and %q", *port, fs.Arg(0))
+		}
+	})
+
+	t.Run("help sentinel", func(t *testing.T) {
+		fs := New("test")
+		os.Args = []string{"test", "--help"}
+		if err := fs.Run(); !errors.Is(err, ErrHelp) {
+			t.Errorf("Expected ErrHelp, got %v", err)
+		}
+	})
+
+	t.Run("version sentinel", func(t *testing.T) {
+		fs := New("test")
+		fs.SetVersion("v1.2.3")
+		os.Args = []string{"test", "--version"}
+		if err := fs.Run(); !errors.Is(err, ErrVersion) {
+			t.Errorf("Expected ErrVersion, got %v", err)
+		}
+	})
+
+	t.Run("version without SetVersion is unknown", func(t *testing.T) {
+		fs := New("test")
+		os.Args = []string{"test", "--version"}
+		if err := fs.Run(); err == nil || errors.Is(err, ErrVersion) {
+			t.Errorf("Expected a regular error without SetVersion, got %v", err)
+		}
+	})
+
+	t.Run("no arguments", func(t *testing.T) {
+		fs := New("test")
+		os.Args = []string{"test"}
+		if err := fs.Run(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+// TestCoerceConfigSlices tests stringification of non-string config array elements
+func TestCoerceConfigSlices(t *testing.T) {
+	configContent := `{"ports": [80, 443, "8080", true, 1.5]}`
+	tmpfile := createTempConfigFile(t, configContent, "test-coerce-*.json")
+	defer func() { _ = os.Remove(tmpfile) }()
+
+	t.Run("strict by default", func(t *testing.T) {
+		fs := New("test")
+		fs.StringSlice("ports", nil, "Ports")
+		fs.SetConfigFile(tmpfile)
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: failed to set flag ports from config: expected string array for flag ports, got float64 in array", "Expected strict error")
+	})
+
+	t.Run("mixed array coerced when enabled", func(t *testing.T) {
+		fs := New("test")
+		ports := fs.StringSlice("ports", nil, "Ports")
+		fs.SetConfigFile(tmpfile)
+		fs.SetCoerceConfigSlices(true)
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		expected := []string{"80", "443", "8080", "true", "1.5"}
+		if len(*ports) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, *ports)
+		}
+		for i := range expected {
+			if (*ports)[i] != expected[i] {
+				t.Errorf("Expected ports[%d] = %q, got %q", i, expected[i], (*ports)[i])
+			}
+		}
+	})
+}
+
+// TestEnvConfigurationAccessors tests reading back env lookup configuration
+func TestEnvConfigurationAccessors(t *testing.T) {
+	fs := New("test")
+	if fs.EnvPrefix() != "" || fs.EnvLookupEnabled() {
+		t.Errorf("Expected no prefix and lookup disabled, got %q and %t", fs.EnvPrefix(), fs.EnvLookupEnabled())
+	}
+
+	fs.EnableEnvLookup()
+	if !fs.EnvLookupEnabled() || fs.EnvPrefix() != "" {
+		t.Errorf("Expected lookup enabled without prefix, got %q and %t", fs.EnvPrefix(), fs.EnvLookupEnabled())
+	}
+
+	fs2 := New("test")
+	fs2.SetEnvPrefix("MYAPP")
+	if fs2.EnvPrefix() != "MYAPP" || !fs2.EnvLookupEnabled() {
+		t.Errorf("Expected prefix MYAPP and lookup enabled, got %q and %t", fs2.EnvPrefix(), fs2.EnvLookupEnabled())
+	}
+}
+
+// TestEnvPrefixFor tests mixing global and per-flag environment prefixes
+func TestEnvPrefixFor(t *testing.T) {
+	fs := New("test")
+	host := fs.String("host", "localhost", "Host")
+	token := fs.String("token", "", "Token")
+	dbURL := fs.String("db-url", "", "Database URL")
+	fs.SetEnvPrefix("NEWAPP")
+	if err := fs.SetEnvPrefixFor("token", "LEGACY"); err != nil {
+		t.Fatalf("SetEnvPrefixFor failed: %v", err)
+	}
+	_ = fs.SetEnvPrefixFor("db-url", "LEGACY")
+	_ = fs.SetEnvVar("db-url", "DATABASE_URL")
+
+	envs := map[string]string{
+		"NEWAPP_HOST":  "example.com",
+		"NEWAPP_TOKEN": "ignored",
+		"LEGACY_TOKEN": "secret",
+		"DATABASE_URL": "postgres",
+	}
+	for k, v := range envs {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range envs {
+			_ = os.Unsetenv(k)
+		}
+	}()
+
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "example.com" {
+		t.Errorf("Expected host from NEWAPP_HOST, got %q", *host)
+	}
+	if *token != "secret" {
+		t.Errorf("Expected token from LEGACY_TOKEN, got %q", *token)
+	}
+	if *dbURL != "postgres" {
+		t.Errorf("Expected custom env var to win over per-flag prefix, got %q", *dbURL)
+	}
+
+	verifyExpectedError(t, fs.SetEnvPrefixFor("missing", "X"), "flag missing not found", "Expected not found error")
+}
+
+// TestEnvSeparator tests custom list separators for string slices loaded from env
+func TestEnvSeparator(t *testing.T) {
+	fs := New("test")
+	hosts := fs.StringSlice("hosts", nil, "Hosts")
+	fs.String("name", "", "Name")
+	fs.SetEnvPrefix("SEPTEST")
+	if err := fs.SetEnvSeparator("hosts", " "); err != nil {
+		t.Fatalf("SetEnvSeparator failed: %v", err)
+	}
+
+	_ = os.Setenv("SEPTEST_HOSTS", "a.example.com  b.example.com")
+	defer func() { _ = os.Unsetenv("SEPTEST_HOSTS") }()
+
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(*hosts) != 2 || (*hosts)[0] != "a.example.com" || (*hosts)[1] != "b.example.com" {
+		t.Errorf("Expected [a.example.com b.example.com], got %v", *hosts)
+	}
+
+	// CLI values still use commas
+	if err := fs.Parse([]string{"--hosts", "c,d"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(*hosts) != 2 || (*hosts)[0] != "c" {
+		t.Errorf("Expected [c d], got %v", *hosts)
+	}
+
+	verifyExpectedError(t, fs.SetEnvSeparator("name", ";"), "flag --name is not a string slice flag", "Expected type error")
+	verifyExpectedError(t, fs.SetEnvOSPathSeparator("missing"), "flag missing not found", "Expected not found error")
+}
+
+// TestEnvCollisions tests detection of flags mapping to the same env var
+func TestEnvCollisions(t *testing.T) {
+	t.Run("custom env var collides with derived name", func(t *testing.T) {
+		fs := New("test")
+		fs.String("db-host", "", "Database host")
+		fs.String("host", "", "Host")
+		fs.SetEnvPrefix("APP")
+		_ = fs.SetEnvVar("host", "APP_DB_HOST")
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "environment variable error: flags --db-host and --host both map to env var APP_DB_HOST", "Expected collision error")
+	})
+
+	t.Run("hyphen and underscore names collide", func(t *testing.T) {
+		fs := New("test")
+		fs.String("log-level", "", "Log level")
+		fs.String("log_level", "", "Log level")
+		fs.EnableEnvLookup()
+
+		err := fs.LoadEnvironmentVariables()
+		verifyExpectedError(t, err, "flags --log-level and --log_level both map to env var LOG_LEVEL", "Expected collision error")
+	})
+
+	t.Run("no collision when env lookup disabled", func(t *testing.T) {
+		fs := New("test")
+		fs.String("log-level", "", "Log level")
+		fs.String("log_level", "", "Log level")
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+// captureStdout runs fn and returns everything it wrote to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	done := make(chan string)
+	go func() {
+		var buf strings.Builder
+		_, _ = io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	fn()
+	_ = w.Close()
+	return <-done
+}
+
+// TestAutoPrintHelp tests suppressing the automatic help output
+func TestAutoPrintHelp(t *testing.T) {
+	t.Run("prints by default", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "", "Server host")
+		var err error
+		out := captureStdout(t, func() { err = fs.Parse([]string{"--help"}) })
+		if !errors.Is(err, ErrHelp) {
+			t.Errorf("Expected ErrHelp, got %v", err)
+		}
+		if !strings.Contains(out, "Server host") {
+			t.Errorf("Expected help output, got %q", out)
+		}
+	})
+
+	t.Run("quiet when disabled", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "", "Server host")
+		fs.SetAutoPrintHelp(false)
+		var err error
+		out := captureStdout(t, func() { err = fs.Parse([]string{"-h"}) })
+		if !errors.Is(err, ErrHelp) {
+			t.Errorf("Expected ErrHelp, got %v", err)
+		}
+		if out != "" {
+			t.Errorf("Expected no output, got %q", out)
+		}
+	})
+}
+
+// TestHelpPager tests that the pager is bypassed when stdout is not a terminal
+func TestHelpPager(t *testing.T) {
+	fs := New("test")
+	for i := 0; i < 50; i++ {
+		fs.String(fmt.Sprintf("flag-%d", i), "", "Generated flag")
+	}
+	fs.SetHelpPager(true)
+
+	_ = os.Setenv("PAGER", "false")
+	_ = os.Setenv("LINES", "10")
+	defer func() {
+		_ = os.Unsetenv("PAGER")
+		_ = os.Unsetenv("LINES")
+	}()
+
+	out := captureStdout(t, fs.PrintHelp)
+	if len(out) != len(fs.Help()) || !strings.Contains(out, "--flag-49") {
+		t.Errorf("Expected plain help output when stdout is not a terminal, got %q", out)
+	}
+
+	tmp, err := os.CreateTemp("", "pager-*.txt")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	defer func() { _ = tmp.Close() }()
+	if isTerminal(tmp) {
+		t.Errorf("Expected regular file not to be a terminal")
+	}
+}
+
+// TestTypeLabels tests default and custom type labels in help
+func TestTypeLabels(t *testing.T) {
+	fs := New("test")
+	fs.StringSlice("tags", nil, "Tags")
+	fs.Float64("rate", 1.0, "Rate")
+	fs.Duration("timeout", time.Second, "Timeout")
+	fs.String("endpoint", "", "Endpoint")
+	fs.Bool("debug", false, "Debug")
+	fs.Bool("tls", false, "TLS")
+	if err := fs.SetTypeLabel("endpoint", "URL"); err != nil {
+		t.Fatalf("SetTypeLabel failed: %v", err)
+	}
+	_ = fs.SetTypeLabel("tls", "BOOL")
+
+	help := fs.Help()
+	for _, expected := range []string{"--tags LIST", "--rate FLOAT", "--timeout DURATION", "--endpoint URL", "--tls BOOL"} {
+		if !strings.Contains(help, expected) {
+			t.Errorf("Expected help to contain %q, got:\n%s", expected, help)
+		}
+	}
+	if strings.Contains(help, "STRINGSLICE") || strings.Contains(help, "--debug BOOL") {
+		t.Errorf("Unexpected label in help:\n%s", help)
+	}
+
+	verifyExpectedError(t, fs.SetTypeLabel("missing", "X"), "flag not found: missing", "Expected not found error")
+}
+
+// TestValueName tests value placeholders in help
+func TestValueName(t *testing.T) {
+	fs := New("test")
+	fs.StringVar("host", "H", "localhost", "Server host")
+	fs.Int("port", 8080, "Server port")
+	fs.Int("workers", 4, "Workers")
+	if err := fs.SetValueName("host", "address"); err != nil {
+		t.Fatalf("SetValueName failed: %v", err)
+	}
+	_ = fs.SetValueName("port", "number")
+	_ = fs.SetTypeLabel("port", "PORT")
+
+	help := fs.Help()
+	for _, expected := range []string{"-H, --host <address>", "--port <number>", "--workers INT"} {
+		if !strings.Contains(help, expected) {
+			t.Errorf("Expected help to contain %q, got:\n%s", expected, help)
+		}
+	}
+
+	verifyExpectedError(t, fs.SetValueName("missing", "x"), "flag not found: missing", "Expected not found error")
+}
+
+// TestCheckConsistency tests each flag setup consistency check
+func TestCheckConsistency(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(fs *FlagSet)
+		expected string
+	}{
+		{
+			name: "redefined flag",
+			setup: func(fs *FlagSet) {
+				fs.String("host", "", "Host")
+				fs.String("host", "", "Host again")
+			},
+			expected: "flag --host defined more than once",
+		},
+		{
+			name: "invalid short key length",
+			setup: func(fs *FlagSet) {
+				fs.IntVar("port", "pp", 80, "Port")
+			},
+			expected: `flag --port has invalid short key "pp": must be a single character`,
+		},
+		{
+			name: "short key conflict",
+			setup: func(fs *FlagSet) {
+				fs.IntVar("port", "p", 80, "Port")
+				fs.StringVar("path", "p", ".", "Path")
+			},
+			expected: "short flag -p used by both --path and --port",
+		},
+		{
+			name: "missing dependency reference",
+			setup: func(fs *FlagSet) {
+				fs.String("tls-cert", "", "Cert")
+				_ = fs.SetDependencies("tls-cert", "tls")
+			},
+			expected: "flag --tls-cert depends on non-existent flag --tls",
+		},
+		{
+			name: "dependency cycle",
+			setup: func(fs *FlagSet) {
+				fs.String("a", "", "A")
+				fs.String("b", "", "B")
+				_ = fs.SetDependencies("a", "b")
+				_ = fs.SetDependencies("b", "a")
+			},
+			expected: "dependency cycle: a → b → a",
+		},
+		{
+			name: "env var collision",
+			setup: func(fs *FlagSet) {
+				fs.String("log-level", "", "Level")
+				fs.String("log_level", "", "Level")
+				fs.EnableEnvLookup()
+			},
+			expected: "flags --log-level and --log_level both map to env var LOG_LEVEL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := New("test")
+			tt.setup(fs)
+			verifyExpectedError(t, fs.CheckConsistency(), tt.expected, "Expected consistency error")
+		})
+	}
+
+	t.Run("multiple problems are joined", func(t *testing.T) {
+		fs := New("test")
+		fs.IntVar("port", "p", 80, "Port")
+		fs.StringVar("path", "p", ".", "Path")
+		fs.String("x", "", "X")
+		_ = fs.SetDependencies("x", "missing")
+
+		err := fs.CheckConsistency()
+		if err == nil || !strings.Contains(err.Error(), "short flag -p") || !strings.Contains(err.Error(), "non-existent flag --missing") {
+			t.Errorf("Expected both problems reported, got %v", err)
+		}
+	})
+
+	t.Run("clean pass", func(t *testing.T) {
+		fs := New("test")
+		fs.IntVar("port", "p", 80, "Port")
+		fs.StringVar("host", "H", "", "Host")
+		_ = fs.SetDependencies("port", "host")
+		fs.SetEnvPrefix("APP")
+		if err := fs.CheckConsistency(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("strict setup runs at parse", func(t *testing.T) {
+		fs := New("test")
+		fs.SetStrictSetup(true)
+		fs.IntVar("port", "p", 80, "Port")
+		fs.StringVar("path", "p", ".", "Path")
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "flag setup error: short flag -p used by both --path and --port", "Expected setup error")
+	})
+}
+
+// TestRenamedFlags tests routing deprecated flag names to their replacement
+func TestRenamedFlags(t *testing.T) {
+	t.Run("value lands on new flag", func(t *testing.T) {
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		verbose := fs.Bool("verbose", false, "Verbose")
+		if err := fs.SetRenamedTo("old-port", "port"); err != nil {
+			t.Fatalf("SetRenamedTo failed: %v", err)
+		}
+		_ = fs.SetRenamedTo("chatty", "verbose")
+
+		if err := fs.Parse([]string{"--old-port", "9090", "--chatty"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *port != 9090 || !*verbose {
+			t.Errorf("Expected port 9090 and verbose, got %d and %t", *port, *verbose)
+		}
+		if !fs.Changed("port") || fs.Changed("old-port") || fs.Lookup("old-port") != nil {
+			t.Errorf("Expected only the new flag to exist and be changed")
+		}
+
+		warnings := fs.Warnings()
+		if len(warnings) != 2 || warnings[0] != "flag --old-port is deprecated, use --port instead" {
+			t.Errorf("Unexpected warnings: %v", warnings)
+		}
+	})
+
+	t.Run("equals syntax and config key", func(t *testing.T) {
+		tmpfile := createTempConfigFile(t, `{"old-host": "config-host"}`, "test-renamed-*.json")
+		defer func() { _ = os.Remove(tmpfile) }()
+
+		fs := New("test")
+		host := fs.String("host", "", "Host")
+		port := fs.Int("port", 0, "Port")
+		_ = fs.SetRenamedTo("old-host", "host")
+		_ = fs.SetRenamedTo("old-port", "port")
+		fs.SetConfigFile(tmpfile)
+
+		if err := fs.Parse([]string{"--old-port=81"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "config-host" || *port != 81 {
+			t.Errorf("Expected host from config and port 81, got %q and %d", *host, *port)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 0, "Port")
+		fs.Int("legacy", 0, "Legacy")
+		verifyExpectedError(t, fs.SetRenamedTo("old", "missing"), "flag not found: missing", "Expected not found error")
+		verifyExpectedError(t, fs.SetRenamedTo("legacy", "port"), "flag --legacy is already defined", "Expected already defined error")
+	})
+}
+
+// TestSingleDashLongFlags tests stdlib-style single-dash long flags
+func TestSingleDashLongFlags(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		fs := New("test")
+		verbose := fs.Bool("verbose", false, "Verbose")
+		port := fs.Int("port", 8080, "Port")
+		debug := fs.BoolVar("debug", "d", false, "Debug")
+		all := fs.BoolVar("all", "a", false, "All")
+		fs.SetSingleDashLongFlags(true)
+
+		if err := fs.Parse([]string{"-verbose", "-port", "9090", "-da"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if !*verbose || *port != 9090 || !*debug || !*all {
+			t.Errorf("Unexpected values: verbose=%t port=%d debug=%t all=%t", *verbose, *port, *debug, *all)
+		}
+
+		fs.SetAutoPrintHelp(false)
+		if err := fs.Parse([]string{"-help"}); !errors.Is(err, ErrHelp) {
+			t.Errorf("Expected ErrHelp for -help, got %v", err)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		fs := New("test")
+		fs.Bool("verbose", false, "Verbose")
+
+		err := fs.Parse([]string{"-verbose"})
+		verifyExpectedError(t, err, "unknown flag: -v in combined sequence -verbose", "Expected combined short flag error")
+	})
+}
+
+// TestValidationErrors tests collecting every validator failure in a single Parse
+func TestValidationErrors(t *testing.T) {
+	setup := func() *FlagSet {
+		fs := New("test")
+		fs.Int("port", 8080, "Port")
+		fs.String("name", "ok", "Name")
+		fs.Int("workers", 4, "Workers")
+		_ = fs.SetValidator("port", portValidator())
+		_ = fs.SetValidator("name", func(val interface{}) error {
+			if val.(string) == "" {
+				return fmt.Errorf("name cannot be empty")
+			}
+			return nil
+		})
+		_ = fs.SetValidator("workers", func(val interface{}) error { return nil })
+		return fs
+	}
+
+	t.Run("collect mode reports all failures", func(t *testing.T) {
+		fs := setup()
+		fs.SetCollectValidationErrors(true)
+
+		err := fs.Parse([]string{"--port=0", "--name="})
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		verrs := fs.ValidationErrors()
+		if len(verrs) != 2 {
+			t.Fatalf("Expected 2 validation errors, got %v", verrs)
+		}
+		if !strings.Contains(verrs[0].Error(), "--name") || !strings.Contains(verrs[1].Error(), "--port") {
+			t.Errorf("Expected errors for --name and --port, got %v", verrs)
+		}
+		if !strings.Contains(err.Error(), "--name") || !strings.Contains(err.Error(), "--port") {
+			t.Errorf("Expected joined error to mention both flags, got %v", err)
+		}
+
+		// A successful parse clears the errors
+		if err := fs.Parse([]string{"--port", "8081", "--name", "x"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if len(fs.ValidationErrors()) != 0 {
+			t.Errorf("Expected no validation errors, got %v", fs.ValidationErrors())
+		}
+	})
+
+	t.Run("default mode stops at first failure", func(t *testing.T) {
+		fs := setup()
+		err := fs.Parse([]string{"--port=0", "--name="})
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+		if len(fs.ValidationErrors()) != 1 {
+			t.Errorf("Expected 1 validation error, got %v", fs.ValidationErrors())
+		}
+	})
+}
+
+// TestEnvBoolOverridesConfig tests env values disabling a bool set to true by config
+func TestEnvBoolOverridesConfig(t *testing.T) {
+	tmpfile := createTempConfigFile(t, `{"debug": true, "verbose": true}`, "test-envbool-*.json")
+	defer func() { _ = os.Remove(tmpfile) }()
+
+	tests := []struct {
+		envValue string
+		flexible bool
+	}{
+		{"0", false},
+		{"false", false},
+		{"off", true},
+		{"NO", true},
+		{"n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.envValue, func(t *testing.T) {
+			fs := New("test")
+			debug := fs.Bool("debug", true, "Debug")
+			verbose := fs.Bool("verbose", false, "Verbose")
+			fs.SetConfigFile(tmpfile)
+			fs.SetEnvPrefix("ENVBOOL")
+			fs.SetFlexibleBool(tt.flexible)
+
+			_ = os.Setenv("ENVBOOL_DEBUG", tt.envValue)
+			defer func() { _ = os.Unsetenv("ENVBOOL_DEBUG") }()
+
+			if err := fs.Parse([]string{}); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if *debug {
+				t.Errorf("Expected env %q to set debug false over config true", tt.envValue)
+			}
+			if !*verbose {
+				t.Errorf("Expected verbose true from config")
+			}
+		})
+	}
+
+	t.Run("off rejected without flexible bool", func(t *testing.T) {
+		fs := New("test")
+		fs.Bool("debug", true, "Debug")
+		fs.SetEnvPrefix("ENVBOOL")
+
+		_ = os.Setenv("ENVBOOL_DEBUG", "off")
+		defer func() { _ = os.Unsetenv("ENVBOOL_DEBUG") }()
+
+		if err := fs.Parse([]string{}); err == nil {
+			t.Error("Expected error for 'off' without flexible bool")
+		}
+	})
+
+	t.Run("CLI still wins over env", func(t *testing.T) {
+		fs := New("test")
+		debug := fs.Bool("debug", false, "Debug")
+		fs.SetEnvPrefix("ENVBOOL")
+		fs.SetFlexibleBool(true)
+
+		_ = os.Setenv("ENVBOOL_DEBUG", "off")
+		defer func() { _ = os.Unsetenv("ENVBOOL_DEBUG") }()
+
+		if err := fs.Parse([]string{"--debug=yes"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if !*debug {
+			t.Errorf("Expected CLI value to win")
+		}
+	})
+}
+
+// TestArgsAllocations tests that remaining-argument access does not allocate
+func TestArgsAllocations(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	if err := fs.Parse([]string{"--host", "x", "file1", "file2"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := 0; i < fs.NArg(); i++ {
+			_ = fs.Arg(i)
+		}
+		_ = fs.Args()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected zero allocations, got %v", allocs)
+	}
+
+	// Appending to the returned slice must not affect the FlagSet
+	args := append(fs.Args(), "extra")
+	args[0] = "changed"
+	if fs.NArg() != 2 || fs.Arg(0) != "file1" {
+		t.Errorf("Expected FlagSet args unchanged, got %v", fs.Args())
+	}
+
+	fs.SetArgsDefensiveCopy(true)
+	copied := fs.Args()
+	copied[0] = "changed"
+	if fs.Arg(0) != "file1" {
+		t.Errorf("Expected defensive copy, got %q", fs.Arg(0))
+	}
+}
+
+// TestGenerateJSONSchema tests JSON Schema generation for config files
+func TestGenerateJSONSchema(t *testing.T) {
+	fs := New("myapp")
+	fs.SetDescription("My application")
+	fs.String("host", "localhost", "Server host")
+	fs.Int("port", 8080, "Server port")
+	fs.Bool("debug", false, "Debug mode")
+	fs.Float64("rate", 1.5, "Rate")
+	fs.Duration("timeout", 30*time.Second, "Timeout")
+	fs.StringSlice("tags", []string{"a"}, "Tags")
+	_ = fs.SetIntRange("port", 1, 65535)
+
+	data, err := fs.GenerateJSONSchema()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema failed: %v", err)
+	}
+
+	var schema struct {
+		Schema      string                            `json:"$schema"`
+		Title       string                            `json:"title"`
+		Description string                            `json:"description"`
+		Type        string                            `json:"type"`
+		Properties  map[string]map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema.Title != "myapp" || schema.Type != "object" || schema.Description != "My application" {
+		t.Errorf("Unexpected schema header: %+v", schema)
+	}
+
+	expectedTypes := map[string]string{
+		"host":    "string",
+		"port":    "integer",
+		"debug":   "boolean",
+		"rate":    "number",
+		"timeout": "string",
+		"tags":    "array",
+	}
+	if len(schema.Properties) != len(expectedTypes) {
+		t.Errorf("Expected %d properties, got %d", len(expectedTypes), len(schema.Properties))
+	}
+	for name, expectedType := range expectedTypes {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			t.Errorf("Expected property %s", name)
+			continue
+		}
+		if prop["type"] != expectedType {
+			t.Errorf("Expected %s type %s, got %v", name, expectedType, prop["type"])
+		}
+	}
+
+	port := schema.Properties["port"]
+	if port["minimum"] != float64(1) || port["maximum"] != float64(65535) || port["default"] != float64(8080) {
+		t.Errorf("Unexpected port property: %v", port)
+	}
+	if schema.Properties["timeout"]["default"] != "30s" {
+		t.Errorf("Expected timeout default 30s, got %v", schema.Properties["timeout"]["default"])
+	}
+	if schema.Properties["host"]["description"] != "Server host" {
+		t.Errorf("Expected host description, got %v", schema.Properties["host"]["description"])
+	}
+}
+
+// TestDurationDefaultUnit tests unit-less duration values
+func TestDurationDefaultUnit(t *testing.T) {
+	fs := New("test")
+	timeout := fs.Duration("timeout", time.Second, "Timeout")
+	delay := fs.Duration("delay", 0, "Delay")
+	strict := fs.Duration("strict", 0, "Strict")
+	fs.String("name", "", "Name")
+	if err := fs.SetDurationDefaultUnit("timeout", time.Second); err != nil {
+		t.Fatalf("SetDurationDefaultUnit failed: %v", err)
+	}
+	_ = fs.SetDurationDefaultUnit("delay", time.Millisecond)
+
+	if err := fs.Parse([]string{"--timeout", "30", "--delay", "500"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *timeout != 30*time.Second {
+		t.Errorf("Expected 30s, got %v", *timeout)
+	}
+	if *delay != 500*time.Millisecond {
+		t.Errorf("Expected 500ms, got %v", *delay)
+	}
+
+	if err := fs.Parse([]string{"--timeout", "1m", "--delay=1.5"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *timeout != time.Minute || *delay != 1500*time.Microsecond {
+		t.Errorf("Expected 1m and 1.5ms, got %v and %v", *timeout, *delay)
+	}
+
+	err := fs.Parse([]string{"--strict", "30"})
+	verifyExpectedError(t, err, "invalid duration value for flag --strict: 30", "Expected unit required error")
+	_ = strict
+
+	err = fs.Parse([]string{"--timeout", "abc"})
+	verifyExpectedError(t, err, "invalid duration value for flag --timeout: abc", "Expected invalid duration error")
+
+	verifyExpectedError(t, fs.SetDurationDefaultUnit("name", time.Second), "flag --name is not a duration flag", "Expected type error")
+	verifyExpectedError(t, fs.SetDurationDefaultUnit("delay", 0), "invalid duration unit for flag --delay: 0s", "Expected unit error")
+}
+
+// TestLongUsageAndSince tests extended flag metadata
+func TestLongUsageAndSince(t *testing.T) {
+	fs := New("test")
+	fs.Bool("http2", false, "Enable HTTP/2")
+	fs.StringVar("cert", "c", "", "TLS certificate")
+	if err := fs.SetLongUsage("http2", "Negotiated via ALPN.\nRequires TLS."); err != nil {
+		t.Fatalf("SetLongUsage failed: %v", err)
+	}
+	if err := fs.SetSince("http2", "v1.4.0"); err != nil {
+		t.Fatalf("SetSince failed: %v", err)
+	}
+
+	flag := fs.Lookup("http2")
+	if flag.LongUsage() != "Negotiated via ALPN.\nRequires TLS." || flag.Since() != "v1.4.0" {
+		t.Errorf("Unexpected metadata: %q, %q", flag.LongUsage(), flag.Since())
+	}
+
+	data := fs.HelpData()
+	if len(data) != 2 || data[0].Name != "cert" || data[1].Name != "http2" {
+		t.Fatalf("Expected entries sorted by name, got %+v", data)
+	}
+	if data[1].LongUsage != flag.LongUsage() || data[1].Since != "v1.4.0" || data[1].Usage != "Enable HTTP/2" {
+		t.Errorf("Metadata did not round-trip: %+v", data[1])
+	}
+	if data[0].ShortKey != "c" || data[0].Type != "string" || data[0].LongUsage != "" {
+		t.Errorf("Unexpected cert entry: %+v", data[0])
+	}
+
+	if strings.Contains(fs.Help(), "since") || strings.Contains(fs.Help(), "ALPN") {
+		t.Error("Expected metadata to be omitted from standard help")
+	}
+	fs.SetVerboseHelp(true)
+	help := fs.Help()
+	if !strings.Contains(help, "Enable HTTP/2 [since: v1.4.0]\n") {
+		t.Errorf("Expected since marker in verbose help, got:\n%s", help)
+	}
+	if !strings.Contains(help, "\n"+strings.Repeat(" ", 30)+"Requires TLS.\n") {
+		t.Errorf("Expected indented long usage in verbose help, got:\n%s", help)
+	}
+
+	verifyExpectedError(t, fs.SetLongUsage("missing", "x"), "flag not found: missing", "Expected not found error")
+	verifyExpectedError(t, fs.SetSince("missing", "v1"), "flag not found: missing", "Expected not found error")
+}
+
+// TestHelpAllHiddenDeprecated tests hidden and deprecated flags with --help-all
+func TestHelpAllHiddenDeprecated(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Server host")
+	debug := fs.Bool("debug-internals", false, "Dump internal state")
+	workers := fs.Int("workers", 4, "Worker count")
+	if err := fs.SetHidden("debug-internals"); err != nil {
+		t.Fatalf("SetHidden failed: %v", err)
+	}
+	if err := fs.SetDeprecated("workers", "use --concurrency instead"); err != nil {
+		t.Fatalf("SetDeprecated failed: %v", err)
+	}
+
+	help := fs.Help()
+	if !strings.Contains(help, "--host") {
+		t.Error("Expected visible flag in help")
+	}
+	if strings.Contains(help, "debug-internals") || strings.Contains(help, "workers") {
+		t.Errorf("Expected hidden and deprecated flags to be omitted from help, got:\n%s", help)
+	}
+
+	all := fs.HelpAll()
+	if !strings.Contains(all, "Dump internal state [hidden]") {
+		t.Errorf("Expected hidden marker in help-all, got:\n%s", all)
+	}
+	if !strings.Contains(all, "[deprecated: use --concurrency instead]") {
+		t.Errorf("Expected deprecated marker in help-all, got:\n%s", all)
+	}
+
+	// --help-all is only recognized when enabled
+	if err := fs.Parse([]string{"--help-all"}); err == nil || errors.Is(err, ErrHelp) {
+		t.Errorf("Expected --help-all to be rejected before EnableHelpAll, got %v", err)
+	}
+
+	fs.EnableHelpAll()
+	var parseErr error
+	output := captureStdout(t, func() { parseErr = fs.Parse([]string{"--help-all"}) })
+	if !errors.Is(parseErr, ErrHelp) {
+		t.Errorf("Expected ErrHelp, got %v", parseErr)
+	}
+	if !strings.Contains(output, "debug-internals") || !strings.Contains(output, "workers") {
+		t.Errorf("Expected all flags in --help-all output, got:\n%s", output)
+	}
+
+	// Hidden and deprecated flags still parse; deprecated use is warned
+	if err := fs.Parse([]string{"--debug-internals", "--workers", "8"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !*debug || *workers != 8 {
+		t.Errorf("Expected debug=true workers=8, got %v %d", *debug, *workers)
+	}
+	warnings := fs.Warnings()
+	if len(warnings) != 1 || warnings[0] != "flag --workers is deprecated: use --concurrency instead" {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+
+	verifyExpectedError(t, fs.SetHidden("missing"), "flag not found: missing", "Expected not found error")
+	verifyExpectedError(t, fs.SetDeprecated("host", ""), "deprecation message for flag --host cannot be empty", "Expected empty message error")
+}
+
+// TestEnvPrefixSeparator tests configurable prefix separators
+func TestEnvPrefixSeparator(t *testing.T) {
+	t.Setenv("MYAPP__DB_HOST", "db.example.com")
+	t.Setenv("MYAPP_DB_HOST", "wrong.example.com")
+	t.Setenv("OTHER__PORT", "9090")
+
+	fs := New("test")
+	host := fs.String("db-host", "localhost", "Database host")
+	port := fs.Int("port", 8080, "Port")
+	fs.SetEnvPrefix("MYAPP")
+	fs.SetEnvPrefixSeparator("__")
+	if err := fs.SetEnvPrefixFor("port", "OTHER"); err != nil {
+		t.Fatalf("SetEnvPrefixFor failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "db.example.com" {
+		t.Errorf("Expected db.example.com, got %s", *host)
+	}
+	if *port != 9090 {
+		t.Errorf("Expected 9090, got %d", *port)
+	}
+
+	// Empty separator restores the default
+	fs2 := New("test")
+	host2 := fs2.String("db-host", "localhost", "Database host")
+	fs2.SetEnvPrefix("MYAPP")
+	fs2.SetEnvPrefixSeparator("")
+	if err := fs2.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host2 != "wrong.example.com" {
+		t.Errorf("Expected default separator lookup, got %s", *host2)
+	}
+}
+
+// TestParseOrExit tests exit codes and error output of ParseOrExit
+func TestParseOrExit(t *testing.T) {
+	origExit, origOutput := exitFunc, errorOutput
+	defer func() { exitFunc, errorOutput = origExit, origOutput }()
+
+	run := func(args []string) (int, string) {
+		code := -1
+		var out strings.Builder
+		exitFunc = func(c int) { code = c }
+		errorOutput = &out
+
+		fs := New("myapp")
+		fs.SetVersion("1.0.0")
+		fs.SetAutoPrintHelp(false)
+		fs.Int("port", 8080, "Server port")
+		captureStdout(t, func() { fs.ParseOrExit(args) })
+		return code, out.String()
+	}
+
+	t.Run("success", func(t *testing.T) {
+		if code, out := run([]string{"--port", "9090"}); code != -1 || out != "" {
+			t.Errorf("Expected no exit, got code %d output %q", code, out)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		if code, _ := run([]string{"--help"}); code != 0 {
+			t.Errorf("Expected exit 0, got %d", code)
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		if code, _ := run([]string{"--version"}); code != 0 {
+			t.Errorf("Expected exit 0, got %d", code)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		code, out := run([]string{"--port", "abc"})
+		if code != 2 {
+			t.Errorf("Expected exit 2, got %d", code)
+		}
+		if !strings.HasPrefix(out, "myapp: invalid int value for flag --port: abc\n\n") {
+			t.Errorf("Unexpected error output: %q", out)
+		}
+		if !strings.Contains(out, "Usage: myapp [options]") {
+			t.Errorf("Expected usage in error output, got %q", out)
+		}
+	})
+}
+
+// TestPositionalValidator tests validation of trailing positional arguments
+func TestPositionalValidator(t *testing.T) {
+	fs := New("test")
+	fs.Bool("verbose", false, "Verbose output")
+	fs.SetPositionalValidator(func(arg string) error {
+		if !strings.HasSuffix(arg, ".txt") {
+			return fmt.Errorf("must be a .txt file")
+		}
+		return nil
+	})
+
+	if err := fs.Parse([]string{"a.txt", "--verbose", "b.txt"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if fs.NArg() != 2 {
+		t.Errorf("Expected 2 args, got %d", fs.NArg())
+	}
+
+	err := fs.Parse([]string{"a.txt", "b.txt", "image.png", "c.txt"})
+	verifyExpectedError(t, err, `invalid argument 2 "image.png": must be a .txt file`, "Expected positional validation error")
+
+	// Arguments after -- are validated too
+	err = fs.Parse([]string{"--", "-notes"})
+	verifyExpectedError(t, err, `invalid argument 0 "-notes": must be a .txt file`, "Expected positional validation error after --")
+
+	fs.SetPositionalValidator(nil)
+	if err := fs.Parse([]string{"image.png"}); err != nil {
+		t.Errorf("Expected no validation after clearing validator, got %v", err)
+	}
+}
+
+// TestEnableConfigFlag tests selecting the config file from the command line
+func TestEnableConfigFlag(t *testing.T) {
+	configFile := createTempConfigFile(t, `{"host": "config.example.com", "port": 3000}`, "configflag-*.json")
+
+	t.Run("loads values for unset flags", func(t *testing.T) {
+		fs := New("test")
+		configPath := fs.EnableConfigFlag("config")
+		host := fs.String("host", "localhost", "Host")
+		port := fs.Int("port", 8080, "Port")
+
+		if err := fs.Parse([]string{"--config", configFile, "--port", "9090"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *configPath != configFile {
+			t.Errorf("Expected config flag value %s, got %s", configFile, *configPath)
+		}
+		if *host != "config.example.com" {
+			t.Errorf("Expected host from config, got %s", *host)
+		}
+		if *port != 9090 {
+			t.Errorf("Expected CLI to override config, got %d", *port)
+		}
+	})
+
+	t.Run("equals form overrides SetConfigFile", func(t *testing.T) {
+		other := createTempConfigFile(t, `{"host": "other.example.com"}`, "configflag-other-*.json")
+		fs := New("test")
+		fs.EnableConfigFlag("config")
+		host := fs.String("host", "localhost", "Host")
+		fs.SetConfigFile(other)
+
+		if err := fs.Parse([]string{"--config=" + configFile}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "config.example.com" {
+			t.Errorf("Expected host from --config file, got %s", *host)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fs := New("test")
+		fs.EnableConfigFlag("config")
+		err := fs.Parse([]string{"--config", "/tmp/does-not-exist-flashflags.json"})
+		verifyExpectedError(t, err, "config file error: config file not found: /tmp/does-not-exist-flashflags.json", "Expected missing config error")
+	})
+
+	t.Run("after separator is ignored", func(t *testing.T) {
+		fs := New("test")
+		fs.EnableConfigFlag("config")
+		host := fs.String("host", "localhost", "Host")
+		if err := fs.Parse([]string{"--", "--config", configFile}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "localhost" {
+			t.Errorf("Expected default host, got %s", *host)
+		}
+	})
+}
+
+// TestBootstrapFlags tests resolving bootstrap flags before config and env load
+func TestBootstrapFlags(t *testing.T) {
+	prodConfig := createTempConfigFile(t, `{"host": "prod.example.com", "replicas": 5}`, "bootstrap-prod-*.json")
+	devConfig := createTempConfigFile(t, `{"host": "dev.example.com", "replicas": 1}`, "bootstrap-dev-*.json")
+	configs := map[string]string{"prod": prodConfig, "dev": devConfig}
+
+	newFlagSet := func() (*FlagSet, *string, *int) {
+		fs := New("test")
+		profile := fs.String("profile", "dev", "Deployment profile")
+		host := fs.String("host", "localhost", "Host")
+		replicas := fs.Int("replicas", 0, "Replica count")
+		if err := fs.SetBootstrapFlag("profile"); err != nil {
+			t.Fatalf("SetBootstrapFlag failed: %v", err)
+		}
+		fs.SetBootstrapHook(func() error {
+			path, ok := configs[*profile]
+			if !ok {
+				return fmt.Errorf("unknown profile: %s", *profile)
+			}
+			fs.SetConfigFile(path)
+			return nil
+		})
+		return fs, host, replicas
+	}
+
+	t.Run("profile selects config", func(t *testing.T) {
+		fs, host, replicas := newFlagSet()
+		if err := fs.Parse([]string{"--replicas", "3", "--profile", "prod"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "prod.example.com" {
+			t.Errorf("Expected prod host, got %s", *host)
+		}
+		if *replicas != 3 {
+			t.Errorf("Expected CLI replicas 3, got %d", *replicas)
+		}
+	})
+
+	t.Run("default profile", func(t *testing.T) {
+		fs, host, _ := newFlagSet()
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "dev.example.com" {
+			t.Errorf("Expected dev host, got %s", *host)
+		}
+	})
+
+	t.Run("hook error", func(t *testing.T) {
+		fs, _, _ := newFlagSet()
+		err := fs.Parse([]string{"--profile=staging"})
+		verifyExpectedError(t, err, "bootstrap error: unknown profile: staging", "Expected hook error")
+	})
+
+	t.Run("bool bootstrap flag", func(t *testing.T) {
+		fs := New("test")
+		offline := fs.Bool("offline", false, "Offline mode")
+		fs.String("host", "localhost", "Host")
+		_ = fs.SetBootstrapFlag("offline")
+		var seen bool
+		fs.SetBootstrapHook(func() error {
+			seen = *offline
+			return nil
+		})
+		if err := fs.Parse([]string{"--offline", "--host", "x"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if !seen {
+			t.Error("Expected offline to be resolved before the hook")
+		}
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		fs := New("test")
+		verifyExpectedError(t, fs.SetBootstrapFlag("missing"), "flag not found: missing", "Expected not found error")
+	})
+}
+
+// TestConfigProfile tests reading a single section of the config file
+func TestConfigProfile(t *testing.T) {
+	configFile := createTempConfigFile(t, `{
+		"dev": {"host": "localhost", "port": 8080},
+		"prod": {"host": "api.example.com", "port": 443}
+	}`, "profile-*.json")
+
+	newFlagSet := func() (*FlagSet, *string, *int) {
+		fs := New("test")
+		host := fs.String("host", "", "Host")
+		port := fs.Int("port", 0, "Port")
+		fs.SetConfigFile(configFile)
+		return fs, host, port
+	}
+
+	for _, tc := range []struct{ profile, host string }{
+		{"dev", "localhost"},
+		{"prod", "api.example.com"},
+	} {
+		t.Run(tc.profile, func(t *testing.T) {
+			fs, host, _ := newFlagSet()
+			fs.SetConfigProfile(tc.profile)
+			if err := fs.Parse([]string{}); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if *host != tc.host {
+				t.Errorf("Expected %s, got %s", tc.host, *host)
+			}
+		})
+	}
+
+	t.Run("missing profile", func(t *testing.T) {
+		fs, _, _ := newFlagSet()
+		fs.SetConfigProfile("staging")
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, fmt.Sprintf("config file error: config profile \"staging\" not found in %s", configFile), "Expected missing profile error")
+	})
+
+	t.Run("selected by bootstrap flag", func(t *testing.T) {
+		fs, host, port := newFlagSet()
+		profile := fs.String("profile", "dev", "Config profile")
+		_ = fs.SetBootstrapFlag("profile")
+		fs.SetBootstrapHook(func() error {
+			fs.SetConfigProfile(*profile)
+			return nil
+		})
+		if err := fs.Parse([]string{"--profile", "prod"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *host != "api.example.com" || *port != 443 {
+			t.Errorf("Expected prod values, got %s:%d", *host, *port)
+		}
+	})
+}
+
+// TestConstraintReport tests the constraint status summary
+func TestConstraintReport(t *testing.T) {
+	newFlagSet := func() *FlagSet {
+		fs := New("test")
+		fs.String("api-key", "", "API key")
+		fs.String("host", "localhost", "Host")
+		fs.Bool("tls", false, "Enable TLS")
+		fs.String("tls-cert", "", "TLS certificate")
+		fs.String("log-file", "", "Log file")
+		_ = fs.SetRequired("api-key")
+		_ = fs.SetDependencies("tls-cert", "tls")
+		_ = fs.SetDependencies("log-file", "host")
+		return fs
+	}
+
+	t.Run("passing", func(t *testing.T) {
+		fs := newFlagSet()
+		if err := fs.Parse([]string{"--api-key", "secret", "--tls", "--tls-cert", "cert.pem"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		expected := "Required flags:\n" +
+			"  --api-key: met (cli)\n" +
+			"Dependencies:\n" +
+			"  --log-file requires --host: inactive\n" +
+			"  --tls-cert requires --tls: met (cli)\n"
+		if report := fs.ConstraintReport(); report != expected {
+			t.Errorf("Unexpected report:\n%s\nexpected:\n%s", report, expected)
+		}
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		fs := newFlagSet()
+		if err := fs.Parse([]string{"--tls-cert", "cert.pem"}); err == nil {
+			t.Fatal("Expected constraint error")
+		}
+		expected := "Required flags:\n" +
+			"  --api-key: unmet\n" +
+			"Dependencies:\n" +
+			"  --log-file requires --host: inactive\n" +
+			"  --tls-cert requires --tls: unmet\n"
+		if report := fs.ConstraintReport(); report != expected {
+			t.Errorf("Unexpected report:\n%s\nexpected:\n%s", report, expected)
+		}
+	})
+
+	t.Run("no constraints", func(t *testing.T) {
+		fs := New("test")
+		if report := fs.ConstraintReport(); report != "Required flags: none\nDependencies: none\n" {
+			t.Errorf("Unexpected report: %q", report)
+		}
+	})
+}
+
+// TestNewConcurrent tests registering flags from multiple goroutines (run with -race)
+func TestNewConcurrent(t *testing.T) {
+	fs := NewConcurrent("test")
+	const workers = 8
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			prefix := fmt.Sprintf("plugin%d", w)
+			fs.String(prefix+"-host", "localhost", "Host")
+			fs.IntVar(prefix+"-port", string(rune('a'+w)), 8080+w, "Port")
+			fs.Bool(prefix+"-debug", false, "Debug")
+			if err := fs.SetGroup(prefix+"-host", prefix); err != nil {
+				t.Errorf("SetGroup failed: %v", err)
+			}
+			if _, err := fs.NewFlag(prefix + "-timeout").Duration(time.Second).Build(); err != nil {
+				t.Errorf("Build failed: %v", err)
+			}
+			fs.StringSlice(prefix+"-tags", nil, "Tags")
+
+			// Registration-time setters run while other goroutines keep registering
+			for _, err := range []error{
+				fs.SetEnvVar(prefix+"-host", strings.ToUpper(prefix)+"_HOST"),
+				fs.SetEnvPrefixFor(prefix+"-port", "PLUGIN"),
+				fs.SetEnvSeparator(prefix+"-tags", ";"),
+				fs.SetRenamedTo(prefix+"-addr", prefix+"-host"),
+				fs.SetBootstrapFlag(prefix + "-debug"),
+				fs.SetDefaultWhen(prefix+"-port", prefix+"-debug", func(v interface{}) bool { return v == true }, 9999),
+				fs.SetRequiredOneOfGroups([][]string{{prefix + "-host"}}),
+			} {
+				if err != nil {
+					t.Errorf("Setter failed: %v", err)
+				}
+			}
+			fs.AddConfigPath(prefix)
+			fs.AddConfigGlob(prefix + "/*.json")
+			fs.AddEnvPrefix(strings.ToUpper(prefix))
+		}(w)
+	}
+	wg.Wait()
+
+	count := 0
+	fs.VisitAll(func(*Flag) { count++ })
+	if count != workers*5 {
+		t.Fatalf("Expected %d flags, got %d", workers*5, count)
+	}
+	if len(fs.renamed) != workers || len(fs.bootstrapFlags) != workers || len(fs.configPaths) != workers {
+		t.Errorf("Expected %d entries per setter, got renamed=%d bootstrap=%d paths=%d",
+			workers, len(fs.renamed), len(fs.bootstrapFlags), len(fs.configPaths))
+	}
+
+	_ = fs.SetRequiredOneOfGroups(nil)
+	if err := fs.Parse([]string{"--plugin3-host", "example.com", "-c", "9000"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if fs.GetString("plugin3-host") != "example.com" || fs.GetInt("plugin2-port") != 9000 {
+		t.Errorf("Unexpected values: %s, %d", fs.GetString("plugin3-host"), fs.GetInt("plugin2-port"))
+	}
+}
+
+// TestBytesFlag tests byte size flags from CLI and config
+func TestBytesFlag(t *testing.T) {
+	t.Run("config suffix and number", func(t *testing.T) {
+		configFile := createTempConfigFile(t, `{"max-body": "10MB", "max-upload": 10485760}`, "bytes-*.json")
+		fs := New("test")
+		maxBody := fs.Bytes("max-body", 1024, "Max body")
+		maxUpload := fs.Bytes("max-upload", 0, "Max upload")
+		fs.SetConfigFile(configFile)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *maxBody != 10485760 || *maxUpload != 10485760 {
+			t.Errorf("Expected 10485760 for both, got %d and %d", *maxBody, *maxUpload)
+		}
+		if fs.GetBytes("max-body") != 10485760 {
+			t.Errorf("GetBytes returned %d", fs.GetBytes("max-body"))
+		}
+	})
+
+	t.Run("invalid config values", func(t *testing.T) {
+		for _, content := range []string{`{"size": "10XB"}`, `{"size": -1}`, `{"size": 1.5}`, `{"size": true}`} {
+			configFile := createTempConfigFile(t, content, "bytes-invalid-*.json")
+			fs := New("test")
+			fs.Bytes("size", 0, "Size")
+			fs.SetConfigFile(configFile)
+			if err := fs.Parse([]string{}); err == nil {
+				t.Errorf("Expected error for config %s", content)
+			}
+		}
+	})
+
+	t.Run("cli units", func(t *testing.T) {
+		tests := map[string]int64{
+			"512":   512,
+			"512B":  512,
+			"64kb":  64 << 10,
+			"64KiB": 64 << 10,
+			"10M":   10 << 20,
+			"1.5GB": 3 << 29,
+			"2TB":   2 << 40,
+		}
+		for input, expected := range tests {
+			fs := New("test")
+			size := fs.Bytes("size", 0, "Size")
+			if err := fs.Parse([]string{"--size", input}); err != nil {
+				t.Errorf("Parse(%s) failed: %v", input, err)
+				continue
+			}
+			if *size != expected {
+				t.Errorf("Parse(%s): expected %d, got %d", input, expected, *size)
+			}
+		}
+	})
+
+	t.Run("invalid cli value and reset", func(t *testing.T) {
+		fs := New("test")
+		size := fs.Bytes("size", 1024, "Size")
+		err := fs.Parse([]string{"--size", "10XB"})
+		verifyExpectedError(t, err, "invalid bytes value for flag --size: 10XB", "Expected invalid bytes error")
+
+		if err := fs.Parse([]string{"--size", "2KB"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		fs.Reset()
+		if *size != 1024 {
+			t.Errorf("Expected reset to 1024, got %d", *size)
+		}
+	})
+}
+
+// TestHelpLabels tests custom help markers
+func TestHelpLabels(t *testing.T) {
+	fs := New("test")
+	fs.Int("port", 8080, "Porta")
+	fs.Bool("tls", false, "TLS")
+	fs.String("tls-cert", "", "Certificato")
+	_ = fs.SetRequired("port")
+	_ = fs.SetDependencies("tls-cert", "tls")
+
+	help := fs.Help()
+	if !strings.Contains(help, "Porta (default: 8080) [REQUIRED]") || !strings.Contains(help, "[depends on: tls]") {
+		t.Errorf("Expected default labels, got:\n%s", help)
+	}
+
+	fs.SetHelpLabels("[obbligatorio]", "dipende da", "predefinito")
+	help = fs.Help()
+	if !strings.Contains(help, "Porta (predefinito: 8080) [obbligatorio]") {
+		t.Errorf("Expected custom required and default labels, got:\n%s", help)
+	}
+	if !strings.Contains(help, "Certificato (predefinito: ) [dipende da: tls]") {
+		t.Errorf("Expected custom dependency label, got:\n%s", help)
+	}
+	if strings.Contains(help, "REQUIRED") || strings.Contains(help, "depends on") {
+		t.Errorf("Expected default labels to be replaced, got:\n%s", help)
+	}
+
+	// Empty labels fall back to the defaults
+	fs.SetHelpLabels("", "", "predefinito")
+	help = fs.Help()
+	if !strings.Contains(help, "Porta (predefinito: 8080) [REQUIRED]") {
+		t.Errorf("Expected fallback required label, got:\n%s", help)
+	}
+}
+
+// TestHelpMessages tests localized help headings
+func TestHelpMessages(t *testing.T) {
+	fs := New("myapp")
+	fs.SetVersion("1.0.0")
+	fs.Int("port", 8080, "Porta")
+	fs.SetMessages(map[string]string{
+		"Usage":     "Utilizzo",
+		"[options]": "[opzioni]",
+		"Options":   "Opzioni",
+	})
+
+	help := fs.Help()
+	if !strings.HasPrefix(help, "Utilizzo: myapp [opzioni]\n\n") {
+		t.Errorf("Expected localized usage line, got:\n%s", help)
+	}
+	if !strings.Contains(help, "\nOpzioni:\n") {
+		t.Errorf("Expected localized options heading, got:\n%s", help)
+	}
+	if !strings.Contains(help, "Version: 1.0.0") {
+		t.Errorf("Expected untranslated version heading, got:\n%s", help)
+	}
+	if strings.Contains(help, "Usage:") || strings.Contains(help, "Options:") {
+		t.Errorf("Expected English headings to be replaced, got:\n%s", help)
+	}
+}
+
+// TestEnvBase64 tests decoding base64-encoded environment values
+func TestEnvBase64(t *testing.T) {
+	newFlagSet := func() (*FlagSet, *string) {
+		fs := New("test")
+		token := fs.String("token", "", "API token")
+		fs.SetEnvPrefix("B64TEST")
+		if err := fs.SetEnvBase64("token"); err != nil {
+			t.Fatalf("SetEnvBase64 failed: %v", err)
+		}
+		return fs, token
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("B64TEST_TOKEN", "c2VjcmV0LXRva2Vu")
+		fs, token := newFlagSet()
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *token != "secret-token" {
+			t.Errorf("Expected decoded token, got %q", *token)
+		}
+	})
+
+	t.Run("cli is not decoded", func(t *testing.T) {
+		t.Setenv("B64TEST_TOKEN", "c2VjcmV0LXRva2Vu")
+		fs, token := newFlagSet()
+		if err := fs.Parse([]string{"--token", "c2VjcmV0"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *token != "c2VjcmV0" {
+			t.Errorf("Expected raw CLI value, got %q", *token)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("B64TEST_TOKEN", "not*base64")
+		fs, _ := newFlagSet()
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "environment variable error: invalid environment variable B64TEST_TOKEN=not*base64: invalid base64 value for flag --token: illegal base64 data at input byte 3", "Expected base64 error")
+	})
+
+	t.Run("setup errors", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 0, "Port")
+		verifyExpectedError(t, fs.SetEnvBase64("missing"), "flag missing not found", "Expected not found error")
+		verifyExpectedError(t, fs.SetEnvBase64("port"), "flag --port is not a string flag", "Expected type error")
+	})
+}
+
+// TestStdinAllowed tests reading flag values from stdin
+func TestStdinAllowed(t *testing.T) {
+	origStdin := stdinReader
+	defer func() { stdinReader = origStdin }()
+
+	fs := New("test")
+	password := fs.String("password", "", "Password")
+	token := fs.StringVar("token", "t", "", "Token")
+	output := fs.String("output", "", "Output file")
+	_ = fs.SetStdinAllowed("password")
+	_ = fs.SetStdinAllowed("token")
+
+	stdinReader = strings.NewReader("s3cret\r\nabc123\nrest")
+	if err := fs.Parse([]string{"--password", "-", "-t", "-", "--output=-"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *password != "s3cret" {
+		t.Errorf("Expected password from stdin, got %q", *password)
+	}
+	if *token != "abc123" {
+		t.Errorf("Expected token from second stdin line, got %q", *token)
+	}
+	if *output != "-" {
+		t.Errorf("Expected literal '-' for flag without stdin, got %q", *output)
+	}
+
+	// EOF without newline
+	stdinReader = strings.NewReader("last-line")
+	if err := fs.Parse([]string{"--password=-"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *password != "last-line" {
+		t.Errorf("Expected value up to EOF, got %q", *password)
+	}
+
+	stdinReader = iotest.ErrReader(errors.New("broken pipe"))
+	err := fs.Parse([]string{"--password", "-"})
+	verifyExpectedError(t, err, "failed to read flag --password from stdin: broken pipe", "Expected stdin error")
+
+	verifyExpectedError(t, fs.SetStdinAllowed("missing"), "flag not found: missing", "Expected not found error")
+
+	t.Run("lone dash needs opt-in", func(t *testing.T) {
+		err := fs.Parse([]string{"--output", "-"})
+		verifyExpectedError(t, err, "flag --output requires a value", "dash for flag without stdin")
+	})
+
+	t.Run("overlong line is an error", func(t *testing.T) {
+		stdinReader = strings.NewReader(strings.Repeat("x", 10001) + "\n")
+		err := fs.Parse([]string{"--password", "-"})
+		verifyExpectedError(t, err, "failed to read flag --password from stdin: line longer than 10000 bytes", "overlong stdin")
+
+		stdinReader = strings.NewReader(strings.Repeat("x", 10000))
+		if err := fs.Parse([]string{"--password", "-"}); err != nil || len(*password) != 10000 {
+			t.Errorf("Expected 10000-byte value, got %d bytes (%v)", len(*password), err)
+		}
+	})
+
+	t.Run("bootstrap flag reads stdin once", func(t *testing.T) {
+		fs := New("test")
+		profile := fs.String("profile", "", "Profile")
+		extra := fs.String("extra", "", "Extra")
+		_ = fs.SetStdinAllowed("profile")
+		_ = fs.SetStdinAllowed("extra")
+		_ = fs.SetBootstrapFlag("profile")
+
+		stdinReader = strings.NewReader("prod\nsecond\n")
+		if err := fs.Parse([]string{"--profile", "-", "--extra", "-"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *profile != "prod" || *extra != "second" {
+			t.Errorf("Expected profile=prod extra=second, got %q and %q", *profile, *extra)
+		}
+	})
+}
+
+// TestGetParsed tests lenient numeric getters
+func TestGetParsed(t *testing.T) {
+	fs := New("test")
+	fs.String("workers", "8", "Workers")
+	fs.String("ratio", " 0.75 ", "Ratio")
+	fs.String("name", "server", "Name")
+	fs.Int("port", 8080, "Port")
+	fs.Float64("scale", 2, "Scale")
+	fs.Float64("fraction", 2.5, "Fraction")
+	fs.Bytes("size", 1024, "Size")
+	fs.Bool("debug", true, "Debug")
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if fs.GetInt("workers") != 0 {
+		t.Errorf("Expected strict GetInt to return 0 for string flag")
+	}
+
+	intTests := map[string]int{"workers": 8, "port": 8080, "scale": 2, "size": 1024}
+	for name, expected := range intTests {
+		if got, err := fs.GetIntParsed(name); err != nil || got != expected {
+			t.Errorf("GetIntParsed(%s) = %d, %v; expected %d", name, got, err, expected)
+		}
+	}
+
+	floatTests := map[string]float64{"ratio": 0.75, "workers": 8, "port": 8080, "fraction": 2.5, "size": 1024}
+	for name, expected := range floatTests {
+		if got, err := fs.GetFloat64Parsed(name); err != nil || got != expected {
+			t.Errorf("GetFloat64Parsed(%s) = %v, %v; expected %v", name, got, err, expected)
+		}
+	}
+
+	_, err := fs.GetIntParsed("name")
+	verifyExpectedError(t, err, "cannot convert flag --name value server to int", "Expected conversion error")
+	_, err = fs.GetIntParsed("fraction")
+	verifyExpectedError(t, err, "cannot convert flag --fraction value 2.5 to int", "Expected fractional error")
+	_, err = fs.GetFloat64Parsed("debug")
+	verifyExpectedError(t, err, "cannot convert flag --debug value true to float64", "Expected bool error")
+	_, err = fs.GetIntParsed("missing")
+	verifyExpectedError(t, err, "flag not found: missing", "Expected not found error")
+}
+
+// TestConfigFlagV2Source tests source reporting through the adapter
+func TestConfigFlagV2Source(t *testing.T) {
+	t.Setenv("V2TEST_HOST", "env.example.com")
+	configFile := createTempConfigFile(t, `{"timeout-ms": 500}`, "v2source-*.json")
+
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	fs.Int("port", 8080, "Port")
+	fs.Int("timeout-ms", 100, "Timeout")
+	fs.Bool("debug", false, "Debug")
+	fs.SetEnvPrefix("V2TEST")
+	fs.SetConfigFile(configFile)
+	if err := fs.Parse([]string{"--port", "9090"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	adapter := NewAdapter(fs)
+	expected := map[string]string{"host": "env", "port": "cli", "timeout-ms": "config", "debug": ""}
+	for name, source := range expected {
+		v2, ok := adapter.Lookup(name).(ConfigFlagV2)
+		if !ok {
+			t.Fatalf("Expected %s to implement ConfigFlagV2", name)
+		}
+		if v2.Source() != source {
+			t.Errorf("Expected source %q for %s, got %q", source, name, v2.Source())
+		}
+	}
+
+	visited := 0
+	adapter.VisitAll(func(f ConfigFlag) {
+		if _, ok := f.(ConfigFlagV2); ok {
+			visited++
+		}
+	})
+	if visited != len(expected) {
+		t.Errorf("Expected all visited flags to implement ConfigFlagV2, got %d", visited)
+	}
+}
+
+// percentLimit is the percentage form of the union flag in TestCustomFlag
+type percentLimit float64
+
+// TestCustomFlag tests a flag parsed by a user function into different types
+func TestCustomFlag(t *testing.T) {
+	parseLimit := func(s string) (interface{}, error) {
+		if strings.HasSuffix(s, "%") {
+			p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+			if err != nil || p < 0 || p > 100 {
+				return nil, fmt.Errorf("invalid percentage %q", s)
+			}
+			return percentLimit(p), nil
+		}
+		return strconv.Atoi(s)
+	}
+
+	fs := New("test")
+	limit := fs.Custom("limit", 100, parseLimit, "Request limit")
+
+	if err := fs.Parse([]string{"--limit", "50"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if v, ok := limit.Value().(int); !ok || v != 50 {
+		t.Errorf("Expected int 50, got %#v", limit.Value())
+	}
+
+	if err := fs.Parse([]string{"--limit=50%"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if v, ok := limit.Value().(percentLimit); !ok || v != 50 {
+		t.Errorf("Expected percentLimit 50, got %#v", limit.Value())
+	}
+
+	err := fs.Parse([]string{"--limit=150%"})
+	verifyExpectedError(t, err, `invalid value for flag --limit: invalid percentage "150%"`, "Expected parse error")
+
+	fs.Reset()
+	if limit.Value() != 100 || limit.Changed() {
+		t.Errorf("Expected reset to default 100, got %#v", limit.Value())
+	}
+
+	// Config values are parsed from their text form
+	configFile := createTempConfigFile(t, `{"limit": 25}`, "custom-*.json")
+	fs2 := New("test")
+	limit2 := fs2.Custom("limit", 100, parseLimit, "Request limit")
+	fs2.SetConfigFile(configFile)
+	if err := fs2.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if limit2.Value() != 25 {
+		t.Errorf("Expected 25 from config, got %#v", limit2.Value())
+	}
+	if !strings.Contains(fs2.Help(), "--limit VALUE") {
+		t.Errorf("Expected VALUE type label, got:\n%s", fs2.Help())
+	}
+
+	// Large config numbers reach the parse function in full, not as 1e+06
+	largeFile := createTempConfigFile(t, `{"limit": 1000000}`, "custom-*.json")
+	defer func() { _ = os.Remove(largeFile) }()
+	fs3 := New("test")
+	limit3 := fs3.Custom("limit", 100, parseLimit, "Request limit")
+	fs3.SetConfigFile(largeFile)
+	if err := fs3.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if limit3.Value() != 1000000 {
+		t.Errorf("Expected 1000000 from config, got %#v", limit3.Value())
+	}
+}
+
+// TestDefaultsFile tests layering a defaults file under the config file
+func TestDefaultsFile(t *testing.T) {
+	defaultsFile := createTempConfigFile(t, `{"host": "defaults.example.com", "port": 3000, "workers": 4}`, "defaults-*.json")
+	overrideFile := createTempConfigFile(t, `{"port": 4000}`, "override-*.json")
+
+	fs := New("test")
+	host := fs.String("host", "localhost", "Host")
+	port := fs.Int("port", 8080, "Port")
+	workers := fs.Int("workers", 1, "Workers")
+	fs.SetDefaultsFile(defaultsFile)
+	fs.SetConfigFile(overrideFile)
+
+	if err := fs.Parse([]string{"--workers", "8"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "defaults.example.com" || fs.Changed("host") {
+		t.Errorf("Expected unchanged default from defaults file, got %s (changed=%v)", *host, fs.Changed("host"))
+	}
+	if *port != 4000 || !fs.Changed("port") || fs.Lookup("port").Source() != "config" {
+		t.Errorf("Expected changed override from config file, got %d (changed=%v)", *port, fs.Changed("port"))
+	}
+	if *workers != 8 || !fs.Changed("workers") {
+		t.Errorf("Expected CLI to win over defaults file, got %d", *workers)
+	}
+	if !strings.Contains(fs.Help(), "(default: defaults.example.com)") {
+		t.Errorf("Expected help to show the defaults file value, got:\n%s", fs.Help())
+	}
+
+	fs.Reset()
+	if *host != "defaults.example.com" || *port != 3000 {
+		t.Errorf("Expected Reset to restore defaults file values, got %s:%d", *host, *port)
+	}
+
+	fs2 := New("test")
+	fs2.String("host", "", "Host")
+	fs2.SetDefaultsFile("/tmp/missing-flashflags-defaults.json")
+	if err := fs2.Parse([]string{}); err == nil || !strings.HasPrefix(err.Error(), "defaults file error: failed to read config file") {
+		t.Errorf("Expected missing defaults file error, got %v", err)
+	}
+}
+
+// TestGroupsAndFlagsInGroup tests iterating flags by group
+func TestGroupsAndFlagsInGroup(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	fs.Int("port", 8080, "Port")
+	fs.String("db-host", "localhost", "Database host")
+	fs.String("db-user", "admin", "Database user")
+	fs.Bool("verbose", false, "Verbose")
+	fs.Bool("debug", false, "Debug")
+	_ = fs.SetGroup("host", "Server")
+	_ = fs.SetGroup("port", "Server")
+	_ = fs.SetGroup("db-user", "Database")
+	_ = fs.SetGroup("db-host", "Database")
+
+	groups := fs.Groups()
+	if len(groups) != 2 || groups[0] != "Database" || groups[1] != "Server" {
+		t.Fatalf("Expected [Database Server], got %v", groups)
+	}
+
+	names := func(flags []*Flag) string {
+		var list []string
+		for _, f := range flags {
+			list = append(list, f.Name())
+		}
+		return strings.Join(list, ",")
+	}
+	expected := map[string]string{
+		"Database": "db-host,db-user",
+		"Server":   "host,port",
+		"":         "debug,verbose",
+		"Missing":  "",
+	}
+	for group, want := range expected {
+		if got := names(fs.FlagsInGroup(group)); got != want {
+			t.Errorf("FlagsInGroup(%q) = %s, expected %s", group, got, want)
+		}
+	}
+
+	if len(New("empty").Groups()) != 0 {
+		t.Error("Expected no groups for an empty flag set")
+	}
+}
+
+// TestDurationOverflowAndMax tests overflowing durations and SetMaxDuration
+func TestDurationOverflowAndMax(t *testing.T) {
+	fs := New("test")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout")
+	delay := fs.Duration("delay", 0, "Delay")
+	_ = fs.SetDurationDefaultUnit("delay", time.Hour)
+
+	err := fs.Parse([]string{"--timeout", "999999999999h"})
+	verifyExpectedError(t, err, "duration value for flag --timeout out of range: 999999999999h", "Expected overflow error")
+
+	err = fs.Parse([]string{"--timeout=-999999999999h"})
+	verifyExpectedError(t, err, "duration value for flag --timeout out of range: -999999999999h", "Expected negative overflow error")
+
+	err = fs.Parse([]string{"--delay", "999999999999"})
+	verifyExpectedError(t, err, "duration value for flag --delay out of range: 999999999999", "Expected unit-less overflow error")
+
+	err = fs.Parse([]string{"--timeout", "12parsecs"})
+	verifyExpectedError(t, err, "invalid duration value for flag --timeout: 12parsecs", "Expected invalid duration error")
+
+	if err := fs.SetMaxDuration("timeout", 10*time.Minute); err != nil {
+		t.Fatalf("SetMaxDuration failed: %v", err)
+	}
+	err = fs.Parse([]string{"--timeout", "1h"})
+	verifyExpectedError(t, err, "duration value for flag --timeout exceeds maximum 10m0s: 1h0m0s", "Expected max exceeded error")
+
+	if err := fs.Parse([]string{"--timeout", "10m"}); err != nil {
+		t.Fatalf("Expected maximum itself to be accepted, got %v", err)
+	}
+	if *timeout != 10*time.Minute || *delay != 0 {
+		t.Errorf("Unexpected values: %v, %v", *timeout, *delay)
+	}
+
+	fs.Int("port", 0, "Port")
+	verifyExpectedError(t, fs.SetMaxDuration("port", time.Second), "flag --port is not a duration flag", "Expected type error")
+	verifyExpectedError(t, fs.SetMaxDuration("timeout", 0), "invalid maximum duration for flag --timeout: 0s", "Expected invalid max error")
+	verifyExpectedError(t, fs.SetMaxDuration("missing", time.Second), "flag not found: missing", "Expected not found error")
+}
+
+// TestAllowedValues tests restricted values with case-sensitive and case-insensitive matching
+func TestAllowedValues(t *testing.T) {
+	t.Run("case sensitive", func(t *testing.T) {
+		fs := New("test")
+		level := fs.String("level", "info", "Log level")
+		if err := fs.SetAllowedValues("level", "debug", "info", "warn", "error"); err != nil {
+			t.Fatalf("SetAllowedValues failed: %v", err)
+		}
+		if err := fs.Parse([]string{"--level", "warn"}); err != nil || *level != "warn" {
+			t.Fatalf("Expected warn, got %s (%v)", *level, err)
+		}
+		err := fs.Parse([]string{"--level", "WARN"})
+		verifyExpectedError(t, err, "invalid value for flag --level: WARN (allowed: debug, info, warn, error)", "Expected case-sensitive rejection")
+	})
+
+	t.Run("case insensitive normalizes", func(t *testing.T) {
+		t.Setenv("ENUMTEST_FORMAT", "Json")
+		fs := New("test")
+		level := fs.String("level", "info", "Log level")
+		format := fs.String("format", "text", "Output format")
+		outputs := fs.StringSlice("outputs", nil, "Outputs")
+		fs.SetEnvPrefix("ENUMTEST")
+		_ = fs.SetAllowedValuesIgnoreCase("level", "debug", "info", "warn", "error")
+		_ = fs.SetAllowedValuesIgnoreCase("format", "text", "json")
+		_ = fs.SetAllowedValuesIgnoreCase("outputs", "stdout", "File", "syslog")
+
+		if err := fs.Parse([]string{"--level", "INFO", "--outputs", "STDOUT,file"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *level != "info" || fs.GetString("level") != "info" {
+			t.Errorf("Expected canonical 'info', got %q", *level)
+		}
+		if *format != "json" {
+			t.Errorf("Expected canonical 'json' from env, got %q", *format)
+		}
+		if strings.Join(*outputs, ",") != "stdout,File" {
+			t.Errorf("Expected canonical slice items, got %v", *outputs)
+		}
+
+		err := fs.Parse([]string{"--outputs", "stdout,kafka"})
+		verifyExpectedError(t, err, "invalid value for flag --outputs: kafka (allowed: stdout, File, syslog)", "Expected slice item rejection")
+	})
+
+	t.Run("config", func(t *testing.T) {
+		configFile := createTempConfigFile(t, `{"level": "Debug"}`, "enum-*.json")
+		fs := New("test")
+		level := fs.String("level", "info", "Log level")
+		_ = fs.SetAllowedValuesIgnoreCase("level", "debug", "info")
+		fs.SetConfigFile(configFile)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *level != "debug" {
+			t.Errorf("Expected canonical 'debug' from config, got %q", *level)
+		}
+	})
+
+	t.Run("setup errors", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 0, "Port")
+		fs.String("level", "", "Level")
+		verifyExpectedError(t, fs.SetAllowedValues("missing", "a"), "flag not found: missing", "Expected not found error")
+		verifyExpectedError(t, fs.SetAllowedValues("port", "1"), "flag --port is not a string or string slice flag", "Expected type error")
+		verifyExpectedError(t, fs.SetAllowedValuesIgnoreCase("level"), "no allowed values given for flag --level", "Expected empty set error")
+	})
+}
+
+// TestUnknownHandler tests intercepting unknown flags
+func TestUnknownHandler(t *testing.T) {
+	fs := New("test")
+	verbose := fs.BoolVar("verbose", "v", false, "Verbose")
+	port := fs.Int("port", 8080, "Port")
+
+	seen := map[string]string{}
+	fs.SetUnknownHandler(func(name, value string) error {
+		if name == "fail" {
+			return fmt.Errorf("unsupported flag: --%s", name)
+		}
+		seen[name] = value
+		return nil
+	})
+
+	err := fs.Parse([]string{"--plugin-cache=redis", "--legacy", "-x", "-y=1", "-vz", "--port", "9090", "file.txt"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	expected := map[string]string{"plugin-cache": "redis", "legacy": "", "x": "", "y": "1", "z": ""}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected handled flags %v, got %v", expected, seen)
+	}
+	if !*verbose || *port != 9090 {
+		t.Errorf("Expected known flags to parse, got verbose=%v port=%d", *verbose, *port)
+	}
+	if args := fs.Args(); len(args) != 1 || args[0] != "file.txt" {
+		t.Errorf("Expected [file.txt], got %v", args)
+	}
+
+	err = fs.Parse([]string{"--fail=1"})
+	verifyExpectedError(t, err, "unsupported flag: --fail", "Expected handler error")
+
+	fs.SetUnknownHandler(nil)
+	if err := fs.Parse([]string{"--legacy=1"}); err == nil {
+		t.Error("Expected unknown flag error after clearing the handler")
+	}
+}
+
+// TestAddEnvPrefix tests fallback environment prefixes
+func TestAddEnvPrefix(t *testing.T) {
+	t.Setenv("OLDAPP_DB_HOST", "legacy.example.com")
+	t.Setenv("OLDAPP_PORT", "1111")
+	t.Setenv("NEWAPP_PORT", "2222")
+	t.Setenv("ANCIENT_WORKERS", "3")
+
+	fs := New("test")
+	host := fs.String("db-host", "localhost", "Database host")
+	port := fs.Int("port", 8080, "Port")
+	workers := fs.Int("workers", 1, "Workers")
+	fs.AddEnvPrefix("NEWAPP")
+	fs.AddEnvPrefix("OLDAPP")
+	fs.AddEnvPrefix("ANCIENT")
+
+	if fs.EnvPrefix() != "NEWAPP" {
+		t.Errorf("Expected first prefix to be primary, got %s", fs.EnvPrefix())
+	}
+	if err := fs.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "legacy.example.com" {
+		t.Errorf("Expected legacy value when the new one is absent, got %s", *host)
+	}
+	if *port != 2222 {
+		t.Errorf("Expected new prefix to win, got %d", *port)
+	}
+	if *workers != 3 {
+		t.Errorf("Expected later fallback prefix, got %d", *workers)
+	}
+
+	// Per-flag prefixes are not affected by fallbacks
+	fs2 := New("test")
+	host2 := fs2.String("db-host", "localhost", "Database host")
+	fs2.SetEnvPrefix("NEWAPP")
+	fs2.AddEnvPrefix("OLDAPP")
+	_ = fs2.SetEnvPrefixFor("db-host", "OTHER")
+	if err := fs2.Parse([]string{}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host2 != "localhost" {
+		t.Errorf("Expected no fallback for per-flag prefix, got %s", *host2)
+	}
+}
+
+// TestUnknownFlagSuggestions tests did-you-mean suggestions for unknown flags
+func TestUnknownFlagSuggestions(t *testing.T) {
+	fs := New("test")
+	fs.Int("port", 8080, "Port")
+	fs.String("host", "localhost", "Host")
+	fs.Bool("verbose", false, "Verbose")
+	fs.Duration("timeout", time.Second, "Timeout")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--prot", "8080"}, "unknown flag: --prot (did you mean --port?)"},
+		{[]string{"--verbos"}, "unknown flag: --verbos (did you mean --verbose?)"},
+		{[]string{"--timeuot=5s"}, "unknown flag: --timeuot (did you mean --timeout?)"},
+		{[]string{"--hots", "example.com"}, "unknown flag: --hots (did you mean --host?)"},
+		{[]string{"--database", "x"}, "unknown flag: --database"},
+		{[]string{"--zzz"}, "unknown flag: --zzz"},
+	}
+	for _, tc := range tests {
+		err := fs.Parse(tc.args)
+		verifyExpectedError(t, err, tc.expected, fmt.Sprintf("Unexpected error for %v", tc.args))
+	}
+
+	if d := levenshtein("kitten", "sitting"); d != 3 {
+		t.Errorf("Expected distance 3, got %d", d)
+	}
+}
+
+// TestStrictValues tests rejecting registered flags as values
+func TestStrictValues(t *testing.T) {
+	newFlagSet := func(strict bool) (*FlagSet, *string) {
+		fs := New("test")
+		host := fs.StringVar("host", "H", "localhost", "Host")
+		fs.IntVar("port", "p", 8080, "Port")
+		fs.BoolVar("verbose", "v", false, "Verbose")
+		fs.IntVar("offset", "o", 0, "Offset")
+		fs.SetStrictValues(strict)
+		return fs, host
+	}
+
+	// Without strict values the next flag is consumed silently
+	fs, host := newFlagSet(false)
+	if err := fs.Parse([]string{"-H", "--port", "80"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "--port" {
+		t.Errorf("Expected lenient parsing to consume --port, got %q", *host)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-H", "--port", "80"}, "flag -H is missing a value (got --port)"},
+		{[]string{"-H", "-p", "80"}, "flag -H is missing a value (got -p)"},
+		{[]string{"-vH", "--verbose"}, "flag -H is missing a value (got --verbose)"},
+		{[]string{"--host", "-p", "80"}, "flag --host is missing a value (got -p)"},
+		{[]string{"--host", "--port=80"}, "flag --host is missing a value (got --port=80)"},
+	}
+	for _, tc := range tests {
+		fs, _ := newFlagSet(true)
+		err := fs.Parse(tc.args)
+		verifyExpectedError(t, err, tc.expected, fmt.Sprintf("Unexpected error for %v", tc.args))
+	}
+
+	// Unregistered dash tokens are still values
+	fs, host = newFlagSet(true)
+	if err := fs.Parse([]string{"-H", "-x", "-o", "-5"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *host != "-x" || fs.GetInt("offset") != -5 {
+		t.Errorf("Expected -x and -5 as values, got %q and %d", *host, fs.GetInt("offset"))
+	}
+}
+
+// TestCombinedBooleanFastPath tests all-boolean combined flags and fallback to the general path
+func TestCombinedBooleanFastPath(t *testing.T) {
+	fs := New("test")
+	extract := fs.BoolVar("extract", "x", false, "Extract")
+	verbose := fs.BoolVar("verbose", "v", false, "Verbose")
+	file := fs.StringVar("file", "f", "", "Archive file")
+	if _, err := fs.NewFlag("zip").Short("z").Bool(false).Build(); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"-xvz"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !*extract || !*verbose || !fs.GetBool("zip") {
+		t.Error("Expected all combined booleans to be set")
+	}
+
+	// A trailing value flag falls back to the general path
+	if err := fs.Parse([]string{"-xvf", "archive.tar"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *file != "archive.tar" {
+		t.Errorf("Expected archive.tar, got %s", *file)
+	}
+
+	// Errors are unchanged and nothing is set before them
+	fs.Reset()
+	err := fs.Parse([]string{"-xq"})
+	verifyExpectedError(t, err, "unknown flag: -q in combined sequence -xq", "Expected unknown flag error")
+	err = fs.Parse([]string{"-fx", "a"})
+	verifyExpectedError(t, err, "non-boolean flag -f must be last in combined sequence -fx", "Expected ordering error")
+}
+
+// TestExportEnv tests writing the configuration as shell export lines
+func TestExportEnv(t *testing.T) {
+	fs := New("test")
+	fs.String("db-host", "localhost", "Database host")
+	fs.String("motd", "", "Message of the day")
+	fs.String("greeting", "", "Greeting")
+	fs.Int("port", 8080, "Port")
+	fs.Bool("debug", false, "Debug")
+	fs.Duration("timeout", 30*time.Second, "Timeout")
+	fs.StringSlice("tags", []string{"web", "api"}, "Tags")
+	fs.String("token", "", "Token")
+	fs.SetEnvPrefix("MYAPP")
+	_ = fs.SetEnvVar("token", "API_TOKEN")
+
+	if err := fs.Parse([]string{"--motd", "hello world", "--greeting=it's here", "--debug"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out strings.Builder
+	if err := fs.ExportEnv(&out); err != nil {
+		t.Fatalf("ExportEnv failed: %v", err)
+	}
+	expected := "export MYAPP_DB_HOST=localhost\n" +
+		"export MYAPP_DEBUG=true\n" +
+		"export MYAPP_GREETING='it'\\''s here'\n" +
+		"export MYAPP_MOTD='hello world'\n" +
+		"export MYAPP_PORT=8080\n" +
+		"export MYAPP_TAGS=web,api\n" +
+		"export MYAPP_TIMEOUT=30s\n" +
+		"export API_TOKEN=''\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	if err := fs.ExportEnv(failingWriter{}); err == nil {
+		t.Error("Expected writer error to be returned")
+	}
+}
+
+// failingWriter is an io.Writer that always fails
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+// TestDefaultDisplay tests overriding the default shown in help
+func TestDefaultDisplay(t *testing.T) {
+	fs := New("test")
+	maxBody := fs.Bytes("max-body", 10<<20, "Maximum body size")
+	fs.Int("workers", 0, "Worker count")
+	if !strings.Contains(fs.Help(), "Maximum body size (default: 10485760)") {
+		t.Errorf("Expected raw default before override, got:\n%s", fs.Help())
+	}
+
+	if err := fs.SetDefaultDisplay("max-body", "10MB"); err != nil {
+		t.Fatalf("SetDefaultDisplay failed: %v", err)
+	}
+	_ = fs.SetDefaultDisplay("workers", "number of CPUs")
+	help := fs.Help()
+	if !strings.Contains(help, "Maximum body size (default: 10MB)") {
+		t.Errorf("Expected display override, got:\n%s", help)
+	}
+	if !strings.Contains(help, "Worker count (default: number of CPUs)") {
+		t.Errorf("Expected display override, got:\n%s", help)
+	}
+
+	// The real default is still used by Reset
+	if err := fs.Parse([]string{"--max-body", "1KB"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	fs.Reset()
+	if *maxBody != 10<<20 {
+		t.Errorf("Expected Reset to restore 10485760, got %d", *maxBody)
+	}
+
+	verifyExpectedError(t, fs.SetDefaultDisplay("missing", "x"), "flag not found: missing", "Expected not found error")
+}
+
+// TestGlobalValidator tests cross-flag validation
+func TestGlobalValidator(t *testing.T) {
+	fs := New("test")
+	fs.Int("start", 0, "First row")
+	fs.Int("end", 100, "Last row")
+	fs.SetGlobalValidator(func(fs *FlagSet) error {
+		if start, end := fs.GetInt("start"), fs.GetInt("end"); start >= end {
+			return fmt.Errorf("--start (%d) must be less than --end (%d)", start, end)
+		}
+		return nil
+	})
+
+	if err := fs.Parse([]string{"--start", "10", "--end", "20"}); err != nil {
+		t.Fatalf("Expected valid range, got %v", err)
+	}
+
+	err := fs.Parse([]string{"--start", "50", "--end", "50"})
+	verifyExpectedError(t, err, "validation failed: --start (50) must be less than --end (50)", "Expected global validation error")
+
+	// Per-flag constraints are checked first
+	_ = fs.SetRequired("end")
+	fs.Reset()
+	err = fs.Parse([]string{"--start", "200"})
+	verifyExpectedError(t, err, "required flag --end not provided", "Expected required error before global validator")
+}
+
+// TestRequireUTF8 tests rejecting invalid UTF-8 values
+func TestRequireUTF8(t *testing.T) {
+	fs := New("test")
+	name := fs.String("display-name", "", "Display name")
+	tags := fs.StringSlice("tags", nil, "Tags")
+	raw := fs.String("raw", "", "Raw bytes")
+	_ = fs.SetRequireUTF8("display-name")
+	_ = fs.SetRequireUTF8("tags")
+
+	if err := fs.Parse([]string{"--display-name", "Zoë 日本語 🚀", "--tags", "café,naïve"}); err != nil {
+		t.Fatalf("Expected valid multi-byte UTF-8 to pass, got %v", err)
+	}
+	if *name != "Zoë 日本語 🚀" || len(*tags) != 2 {
+		t.Errorf("Unexpected values: %q, %v", *name, *tags)
+	}
+
+	err := fs.Parse([]string{"--display-name", "bad\xff\xfe"})
+	verifyExpectedError(t, err, "flag --display-name contains invalid UTF-8", "Expected invalid UTF-8 error")
+
+	err = fs.Parse([]string{"--tags", "ok,trunc\xe6\x97"})
+	verifyExpectedError(t, err, "flag --tags item 1 contains invalid UTF-8", "Expected invalid slice item error")
+
+	// Flags without the option accept any bytes
+	if err := fs.Parse([]string{"--raw", "bin\xff"}); err != nil || *raw != "bin\xff" {
+		t.Errorf("Expected raw bytes to be accepted, got %q (%v)", *raw, err)
+	}
+
+	fs.Int("port", 0, "Port")
+	verifyExpectedError(t, fs.SetRequireUTF8("port"), "flag --port is not a string or string slice flag", "Expected type error")
+	verifyExpectedError(t, fs.SetRequireUTF8("missing"), "flag not found: missing", "Expected not found error")
+}
+
+// TestCompletionFunc tests dynamic value completion through the generated script and --complete
+func TestCompletionFunc(t *testing.T) {
+	fs := New("myapp")
+	fs.StringVar("database", "d", "", "Database to connect to")
+	fs.String("level", "info", "Log level")
+	fs.Bool("debug", false, "Debug mode")
+	_ = fs.SetAllowedValues("level", "debug", "info")
+	err := fs.SetCompletionFunc("database", func(partial string) []string {
+		var out []string
+		for _, db := range []string{"orders", "orders_archive", "users"} {
+			if strings.HasPrefix(db, partial) {
+				out = append(out, db)
+			}
+		}
+		return out
+	})
+	if err != nil {
+		t.Fatalf("SetCompletionFunc failed: %v", err)
+	}
+
+	t.Run("script references the flag", func(t *testing.T) {
+		script, err := fs.GenerateCompletion("bash")
+		if err != nil {
+			t.Fatalf("GenerateCompletion failed: %v", err)
+		}
+		for _, want := range []string{
+			"--database|-d)",
+			`myapp --complete database "$cur"`,
+			`compgen -W "debug info"`,
+			"complete -F _myapp_completions myapp",
+		} {
+			if !strings.Contains(script, want) {
+				t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+			}
+		}
+	})
+
+	t.Run("hidden complete flag", func(t *testing.T) {
+		var err error
+		out := captureStdout(t, func() {
+			err = fs.Parse([]string{"--complete", "database", "ord"})
+		})
+		if !errors.Is(err, ErrHelp) {
+			t.Errorf("Expected ErrHelp, got %v", err)
+		}
+		if out != "orders\norders_archive\n" {
+			t.Errorf("Unexpected candidates: %q", out)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := fs.Parse([]string{"--complete", "debug"})
+		verifyExpectedError(t, err, "no completion registered for flag --debug", "Expected missing completion error")
+
+		_, err = fs.GenerateCompletion("fish")
+		verifyExpectedError(t, err, "unsupported completion shell: fish", "Expected unsupported shell error")
+
+		err = fs.SetCompletionFunc("missing", nil)
+		verifyExpectedError(t, err, "flag not found: missing", "Expected not found error")
+	})
+
+	t.Run("conflicting complete flag", func(t *testing.T) {
+		other := New("test")
+		other.String("shell", "", "Shell")
+		complete := other.Bool("complete", false, "Mark the task complete")
+		err := other.SetCompletionFunc("shell", func(string) []string { return nil })
+		verifyExpectedError(t, err, "flag --complete conflicts with the completion flag", "Expected conflict error")
+
+		later := New("test")
+		later.String("shell", "", "Shell")
+		_ = later.SetCompletionFunc("shell", func(string) []string { return nil })
+		complete = later.Bool("complete", false, "Mark the task complete")
+		err = later.CheckConsistency()
+		if err == nil || !strings.Contains(err.Error(), "flag --complete conflicts with the completion flag") {
+			t.Errorf("Expected consistency check to report the conflict, got %v", err)
+		}
+		if err := later.Parse([]string{"--complete"}); err != nil || !*complete {
+			t.Errorf("Expected the user flag to take precedence, got %t (err: %v)", *complete, err)
+		}
+	})
+
+	t.Run("quoted program name", func(t *testing.T) {
+		quoted := New("my app")
+		quoted.String("database", "", "Database")
+		_ = quoted.SetCompletionFunc("database", func(string) []string { return nil })
+		script, _ := quoted.GenerateCompletion("bash")
+		for _, want := range []string{
+			`$('my app' --complete database "$cur" 2>/dev/null)`,
+			"complete -F _my_app_completions 'my app'",
+		} {
+			if !strings.Contains(script, want) {
+				t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+			}
+		}
+	})
+}
+
+// TestSetVersionFromBuildInfo tests deriving the version from build information
+func TestSetVersionFromBuildInfo(t *testing.T) {
+	t.Run("real build info", func(t *testing.T) {
+		fs := New("test")
+		fs.SetVersionFromBuildInfo()
+		if fs.version == "" {
+			t.Error("Expected a non-empty version")
+		}
+	})
+
+	original := readBuildInfo
+	defer func() { readBuildInfo = original }()
+
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		ok   bool
+		want string
+	}{
+		{"missing", nil, false, "dev"},
+		{"module version", &debug.BuildInfo{Main: debug.Module{Version: "v1.2.0"}}, true, "v1.2.0"},
+		{"vcs revision", &debug.BuildInfo{
+			Main: debug.Module{Version: "(devel)"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true, "0123456789ab-dirty"},
+		{"devel without vcs", &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, true, "dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readBuildInfo = func() (*debug.BuildInfo, bool) { return tt.info, tt.ok }
+			fs := New("test")
+			fs.SetVersionFromBuildInfo()
+			if fs.version != tt.want {
+				t.Errorf("Expected version %q, got %q", tt.want, fs.version)
+			}
+		})
+	}
+}
+
+// TestPrintGroupHelp tests printing a single group's help and group error hints
+func TestPrintGroupHelp(t *testing.T) {
+	fs := New("test")
+	fs.String("tls-key", "", "TLS private key file")
+	fs.String("tls-cert", "", "TLS certificate file")
+	fs.String("tls-legacy", "", "Old TLS option")
+	fs.Int("port", 8080, "Server port")
+	_ = fs.SetGroup("tls-cert", "TLS Options")
+	_ = fs.SetGroup("tls-key", "TLS Options")
+	_ = fs.SetGroup("tls-legacy", "TLS Options")
+	_ = fs.SetHidden("tls-legacy")
+	_ = fs.SetRequired("tls-cert")
+
+	out := captureStdout(t, func() { fs.PrintGroupHelp("TLS Options") })
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "TLS Options:" {
+		t.Fatalf("Expected heading and two flags, got %q", out)
+	}
+	if !strings.Contains(lines[1], "--tls-cert") || !strings.Contains(lines[2], "--tls-key") {
+		t.Errorf("Expected flags sorted by name, got %q", out)
+	}
+	if strings.Contains(out, "--port") || strings.Contains(out, "--tls-legacy") {
+		t.Errorf("Expected only visible flags of the group, got %q", out)
+	}
+
+	if ungrouped := fs.GroupHelp(""); !strings.HasPrefix(ungrouped, "Options:\n") || !strings.Contains(ungrouped, "--port") {
+		t.Errorf("Expected ungrouped options section, got %q", ungrouped)
+	}
+	if empty := fs.GroupHelp("Nope"); empty != "" {
+		t.Errorf("Expected empty help for unknown group, got %q", empty)
+	}
+
+	err := fs.Parse([]string{})
+	verifyExpectedError(t, err, "required flag --tls-cert not provided", "Expected plain required error")
+
+	fs.SetGroupErrorHints(true)
+	err = fs.Parse([]string{})
+	verifyExpectedError(t, err, "required flag --tls-cert not provided (see TLS Options)", "Expected group hint")
+}
+
+// TestParseReturnHelp tests returning help text instead of printing it
+func TestParseReturnHelp(t *testing.T) {
+	fs := New("myapp")
+	fs.Int("port", 8080, "Server port")
+	fs.EnableHelpAll()
+
+	var text string
+	var err error
+	out := captureStdout(t, func() {
+		text, err = fs.ParseReturnHelp([]string{"--help"})
+	})
+	if !errors.Is(err, ErrHelp) {
+		t.Errorf("Expected ErrHelp, got %v", err)
+	}
+	if out != "" {
+		t.Errorf("Expected nothing printed, got %q", out)
+	}
+	if !strings.Contains(text, "Usage: myapp [options]") || !strings.Contains(text, "--port") {
+		t.Errorf("Expected returned help text, got %q", text)
+	}
+
+	text, err = fs.ParseReturnHelp([]string{"--help-all"})
+	if !errors.Is(err, ErrHelp) || text != fs.HelpAll() {
+		t.Errorf("Expected HelpAll text, got %q (%v)", text, err)
+	}
+
+	text, err = fs.ParseReturnHelp([]string{"--port", "9090"})
+	if err != nil || text != "" {
+		t.Errorf("Expected no help and no error, got %q (%v)", text, err)
+	}
+
+	// Regular Parse still prints help afterwards
+	out = captureStdout(t, func() { _ = fs.Parse([]string{"-h"}) })
+	if !strings.Contains(out, "--port") {
+		t.Errorf("Expected Parse to print help, got %q", out)
+	}
+}
+
+// TestSetRequiredMessage tests custom errors for missing required flags
+func TestSetRequiredMessage(t *testing.T) {
+	fs := New("test")
+	fs.String("api-key", "", "API key")
+	_ = fs.SetRequired("api-key")
+	if err := fs.SetRequiredMessage("api-key", "--api-key is required; get one at https://example.com/keys"); err != nil {
+		t.Fatalf("SetRequiredMessage failed: %v", err)
+	}
+
+	err := fs.Parse([]string{})
+	verifyExpectedError(t, err, "--api-key is required; get one at https://example.com/keys", "Expected custom required message")
+
+	if err := fs.Parse([]string{"--api-key", "secret"}); err != nil {
+		t.Errorf("Expected success when provided, got %v", err)
+	}
+
+	verifyExpectedError(t, fs.SetRequiredMessage("missing", "x"), "flag not found: missing", "Expected not found error")
+}
+
+// TestCountChanged tests counting provided flags from a set
+func TestCountChanged(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"none provided", []string{}, 0},
+		{"some provided", []string{"--email", "a@b.c"}, 1},
+		{"all provided", []string{"--email", "a@b.c", "--sms", "123", "--webhook", "hook"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := New("test")
+			fs.String("email", "", "Email")
+			fs.String("sms", "", "SMS number")
+			fs.String("webhook", "", "Webhook")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got := fs.CountChanged("email", "sms", "webhook", "unknown"); got != tt.want {
+				t.Errorf("Expected %d changed flags, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestInterpolation tests expanding ${flag} references to other flags
+func TestInterpolation(t *testing.T) {
+	t.Run("simple reference", func(t *testing.T) {
+		fs := New("test")
+		fs.String("data-dir", "/var/lib/app", "Data directory")
+		logFile := fs.String("log-file", "${data-dir}/app.log", "Log file")
+		fs.EnableInterpolation()
+
+		if err := fs.Parse([]string{"--data-dir", "/srv/data"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *logFile != "/srv/data/app.log" || fs.GetString("log-file") != "/srv/data/app.log" {
+			t.Errorf("Expected /srv/data/app.log, got %q", *logFile)
+		}
+	})
+
+	t.Run("chained reference", func(t *testing.T) {
+		fs := New("test")
+		fs.String("root", "/opt", "Root")
+		fs.Int("port", 8080, "Port")
+		fs.String("app-dir", "${root}/app", "App dir")
+		target := fs.String("target", "${app-dir}/run-${port}.pid", "Pid file")
+		fs.EnableInterpolation()
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *target != "/opt/app/run-8080.pid" {
+			t.Errorf("Expected /opt/app/run-8080.pid, got %q", *target)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		fs := New("test")
+		fs.String("a", "${b}", "A")
+		fs.String("b", "x${a}", "B")
+		fs.EnableInterpolation()
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "interpolation cycle: a → b → a", "Expected cycle error")
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		fs := New("test")
+		fs.String("a", "${nope}", "A")
+		fs.EnableInterpolation()
+
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "flag --a references unknown flag --nope", "Expected unknown reference error")
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		fs := New("test")
+		fs.String("root", "/opt", "Root")
+		dir := fs.String("dir", "${root}/x", "Dir")
+		if err := fs.Parse([]string{}); err != nil || *dir != "${root}/x" {
+			t.Errorf("Expected literal value, got %q (%v)", *dir, err)
+		}
+	})
+}
+
+// TestCloneAndDiff tests cloning a flag set and diffing it against the original
+func TestCloneAndDiff(t *testing.T) {
+	fs := New("test")
+	port := fs.IntVar("port", "p", 8080, "Port")
+	fs.String("host", "localhost", "Host")
+	fs.StringSlice("tags", []string{"a"}, "Tags")
+	if err := fs.Parse([]string{"--tags", "x,y"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	clone := fs.Clone()
+	if diff := Diff(fs, clone); len(diff) != 0 {
+		t.Errorf("Expected identical clone, got diff %v", diff)
+	}
+
+	if err := clone.Parse([]string{"-p", "9090"}); err != nil {
+		t.Fatalf("Parse on clone failed: %v", err)
+	}
+	if *port != 8080 || fs.GetInt("port") != 8080 {
+		t.Errorf("Expected original untouched, got %d", *port)
+	}
+
+	diff := Diff(fs, clone)
+	if len(diff) != 1 {
+		t.Fatalf("Expected only port to differ, got %v", diff)
+	}
+	if diff["port"] != [2]interface{}{8080, 9090} {
+		t.Errorf("Unexpected port diff: %v", diff["port"])
+	}
+
+	clone.Bool("extra", false, "Extra")
+	if got := Diff(fs, clone)["extra"]; got != [2]interface{}{nil, false} {
+		t.Errorf("Expected flag only in b to be reported, got %v", got)
+	}
+}
+
+// TestCloneIsIndependent tests that registration on a clone doesn't leak into the original
+func TestCloneIsIndependent(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	fs.Int("port", 8080, "Port")
+	fs.String("profile", "dev", "Profile")
+	_ = fs.SetRenamedTo("server", "host")
+	_ = fs.SetBootstrapFlag("profile")
+
+	clone := fs.Clone()
+	_ = clone.SetRenamedTo("listen", "port")
+	_ = clone.SetBootstrapFlag("host")
+	clone.String("host", "other", "Redefined host")
+
+	if _, ok := fs.renamed["listen"]; ok || len(fs.renamed) != 1 {
+		t.Errorf("Expected original renames untouched, got %v", fs.renamed)
+	}
+	if len(fs.bootstrapFlags) != 1 || fs.bootstrapFlags[0] != "profile" {
+		t.Errorf("Expected original bootstrap flags untouched, got %v", fs.bootstrapFlags)
+	}
+	if len(fs.redefined) != 0 {
+		t.Errorf("Expected original redefinitions untouched, got %v", fs.redefined)
+	}
+	if err := fs.Parse([]string{"--listen", "9090"}); err == nil {
+		t.Error("Expected --listen to be unknown in the original")
+	}
+
+	_ = fs.SetBootstrapFlag("port")
+	if len(clone.bootstrapFlags) != 2 || clone.bootstrapFlags[1] != "host" {
+		t.Errorf("Expected clone bootstrap flags untouched, got %v", clone.bootstrapFlags)
+	}
+}
+
+// TestSetPowerOfTwo tests the power-of-two validator helper
+func TestSetPowerOfTwo(t *testing.T) {
+	fs := New("test")
+	size := fs.Int("buffer-size", 4096, "Buffer size")
+	fs.String("name", "", "Name")
+	if err := fs.SetPowerOfTwo("buffer-size"); err != nil {
+		t.Fatalf("SetPowerOfTwo failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"--buffer-size", "1024"}); err != nil || *size != 1024 {
+		t.Errorf("Expected 1024 to be accepted, got %d (%v)", *size, err)
+	}
+
+	err := fs.Parse([]string{"--buffer-size", "1000"})
+	if err == nil || !strings.Contains(err.Error(), "value 1000 is not a power of two") {
+		t.Errorf("Expected power of two error, got %v", err)
+	}
+
+	err = fs.Parse([]string{"--buffer-size", "0"})
+	if err == nil || !strings.Contains(err.Error(), "value 0 is not a power of two") {
+		t.Errorf("Expected zero to be rejected, got %v", err)
+	}
+
+	verifyExpectedError(t, fs.SetPowerOfTwo("name"), "flag --name is not an int or bytes flag", "Expected type error")
+	verifyExpectedError(t, fs.SetPowerOfTwo("missing"), "flag not found: missing", "Expected not found error")
+}
+
+// TestHelpShowsAllowedValues tests rendering enum choices inline in help
+func TestHelpShowsAllowedValues(t *testing.T) {
+	fs := New("test")
+	fs.String("level", "info", "Log level")
+	fs.String("format", "text", "Output format")
+	_ = fs.SetAllowedValues("level", "debug", "info", "warn", "error")
+	_ = fs.SetAllowedValues("format", "text", "json")
+	_ = fs.SetValueName("format", "FMT")
+
+	help := fs.Help()
+	if !strings.Contains(help, "--level debug|info|warn|error Log level (default: info)") {
+		t.Errorf("Expected choices between flag and description, got:\n%s", help)
+	}
+	if !strings.Contains(help, "--format <FMT>") {
+		t.Errorf("Expected explicit value name to take precedence, got:\n%s", help)
+	}
+}
+
+// TestSetWindowsStyle tests parsing /flag and /flag:value arguments
+func TestSetWindowsStyle(t *testing.T) {
+	newSet := func() (*FlagSet, *int, *bool) {
+		fs := New("test")
+		port := fs.IntVar("port", "p", 8080, "Port")
+		verbose := fs.Bool("verbose", false, "Verbose")
+		return fs, port, verbose
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		fs, port, verbose := newSet()
+		fs.SetWindowsStyle(true)
+		if err := fs.Parse([]string{"/port:9090", "/verbose", "/etc/app.conf"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *port != 9090 || !*verbose {
+			t.Errorf("Expected port 9090 and verbose, got %d, %v", *port, *verbose)
+		}
+		if args := fs.Args(); len(args) != 1 || args[0] != "/etc/app.conf" {
+			t.Errorf("Expected unknown /path to stay positional, got %v", args)
+		}
+
+		if err := fs.Parse([]string{"/p=7070", "--verbose=false"}); err != nil || *port != 7070 || *verbose {
+			t.Errorf("Expected short key and Unix syntax to work together, got %d, %v (%v)", *port, *verbose, err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		fs, port, _ := newSet()
+		if err := fs.Parse([]string{"/port:9090"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *port != 8080 || fs.NArg() != 1 {
+			t.Errorf("Expected /port:9090 to be positional, got port %d, args %v", *port, fs.Args())
+		}
+	})
+}
+
+// TestMerge tests composing flag sets and detecting collisions
+func TestMerge(t *testing.T) {
+	logging := New("logging")
+	level := logging.StringVar("log-level", "l", "info", "Log level")
+	_ = logging.SetGroup("log-level", "Logging")
+	_ = logging.SetAllowedValues("log-level", "debug", "info")
+
+	httpFlags := New("http")
+	port := httpFlags.IntVar("port", "p", 8080, "Port")
+
+	app := New("app")
+	if err := app.Merge(logging); err != nil {
+		t.Fatalf("Merge logging failed: %v", err)
+	}
+	if err := app.Merge(httpFlags); err != nil {
+		t.Fatalf("Merge http failed: %v", err)
+	}
+
+	if err := app.Parse([]string{"-l", "debug", "--port", "9090"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *level != "debug" || *port != 9090 {
+		t.Errorf("Expected merged pointers to be set, got %q, %d", *level, *port)
+	}
+	if got := app.FlagsInGroup("Logging"); len(got) != 1 || got[0].Name() != "log-level" {
+		t.Errorf("Expected group to be preserved, got %v", got)
+	}
+	if err := app.Parse([]string{"--log-level", "trace"}); err == nil {
+		t.Error("Expected allowed values to be preserved")
+	}
+
+	t.Run("name collision", func(t *testing.T) {
+		other := New("other")
+		other.Int("port", 1, "Port again")
+		other.Bool("unrelated", false, "Unrelated")
+		err := app.Merge(other)
+		verifyExpectedError(t, err, "cannot merge flag --port: name already defined", "Expected name collision")
+		if app.Lookup("unrelated") != nil {
+			t.Error("Expected nothing to be imported on collision")
+		}
+	})
+
+	t.Run("short key collision", func(t *testing.T) {
+		other := New("other")
+		other.BoolVar("pretty", "p", false, "Pretty output")
+		err := app.Merge(other)
+		verifyExpectedError(t, err, "cannot merge flag --pretty: short key -p already used by --port", "Expected short key collision")
+	})
+}
+
+// TestCombinedBoolToggles tests explicit +/- suffixes on boolean short flags
+func TestCombinedBoolToggles(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantDebug   bool
+		wantVerbose bool
+	}{
+		{"plain stays true", []string{"-d"}, true, false},
+		{"minus sets false", []string{"-d", "-d-"}, false, false},
+		{"plus sets true", []string{"-d+"}, true, false},
+		{"combined sequence", []string{"-v+d-"}, false, true},
+		{"mixed with plain", []string{"-vd-"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := New("test")
+			debug := fs.BoolVar("debug", "d", false, "Debug")
+			verbose := fs.BoolVar("verbose", "v", false, "Verbose")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if *debug != tt.wantDebug || *verbose != tt.wantVerbose {
+				t.Errorf("Expected debug=%v verbose=%v, got debug=%v verbose=%v", tt.wantDebug, tt.wantVerbose, *debug, *verbose)
+			}
+			if !fs.Changed("debug") {
+				t.Error("Expected debug to be marked changed")
+			}
+		})
+	}
+
+	fs := New("test")
+	fs.IntVar("port", "p", 0, "Port")
+	if err := fs.Parse([]string{"-p-"}); err == nil {
+		t.Error("Expected +/- suffix on a non-boolean flag to fail")
+	}
+}
+
+// TestPositionalArgCountLimits tests SetMinArgs and SetMaxArgs
+func TestPositionalArgCountLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"under", []string{}, "expected at least 1 argument, got 0"},
+		{"exact min", []string{"a"}, ""},
+		{"exact max", []string{"a", "b"}, ""},
+		{"over", []string{"a", "b", "c"}, "expected at most 2 arguments, got 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := New("test")
+			fs.Bool("verbose", false, "Verbose")
+			fs.SetMinArgs(1)
+			fs.SetMaxArgs(2)
+			err := fs.Parse(append([]string{"--verbose"}, tt.args...))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				return
+			}
+			verifyExpectedError(t, err, tt.wantErr, "Expected argument count error")
+		})
+	}
+
+	fs := New("test")
+	fs.SetMaxArgs(2)
+	fs.SetMaxArgs(-1)
+	if err := fs.Parse([]string{"a", "b", "c"}); err != nil {
+		t.Errorf("Expected negative max to remove the limit, got %v", err)
+	}
+}
+
+// TestResultFields tests the structured parse results for logging
+func TestResultFields(t *testing.T) {
+	fs := New("test")
+	fs.Int("port", 8080, "Port")
+	fs.Bool("verbose", false, "Verbose")
+	fs.String("host", "localhost", "Host")
+	fs.String("token", "", "API token")
+	_ = fs.SetSecret("token")
+
+	if err := fs.Parse([]string{"--port", "9090", "--verbose", "--token", "s3cr3t"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	fields := fs.ResultFields()
+	if len(fields) != 2 || fields["port"] != 9090 || fields["verbose"] != true {
+		t.Errorf("Expected only changed non-secret flags, got %v", fields)
+	}
+	if _, exists := fields["token"]; exists {
+		t.Error("Expected secret flag to be omitted")
+	}
+
+	verifyExpectedError(t, fs.SetSecret("missing"), "flag not found: missing", "Expected not found error")
+}
+
+// TestSetBoolWords tests custom truthy and falsey tokens
+func TestSetBoolWords(t *testing.T) {
+	fs := New("test")
+	cache := fs.Bool("cache", false, "Cache")
+	fs.SetBoolWords([]string{"enabled", "Active"}, []string{"disabled", "inactive"})
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"enabled", true},
+		{"ACTIVE", true},
+		{"disabled", false},
+		{"Inactive", false},
+		{"true", true},
+		{"0", false},
+	}
+	for _, tt := range tests {
+		if err := fs.Parse([]string{"--cache=" + tt.value}); err != nil {
+			t.Errorf("Expected %q to be accepted, got %v", tt.value, err)
+			continue
+		}
+		if *cache != tt.want {
+			t.Errorf("Expected %q to give %v, got %v", tt.value, tt.want, *cache)
+		}
+	}
+
+	for _, value := range []string{"maybe", "yes"} {
+		err := fs.Parse([]string{"--cache=" + value})
+		verifyExpectedError(t, err, "invalid bool value for flag --cache: "+value, "Expected token outside the sets to be rejected")
+	}
+}
+
+// TestSetConfigEnvVar tests reading the config file path from an environment variable
+func TestSetConfigEnvVar(t *testing.T) {
+	path := createTempConfigFile(t, `{"port": 9090}`, "test-config-env-*.json")
+	defer func() { _ = os.Remove(path) }()
+
+	t.Run("loads file from env var", func(t *testing.T) {
+		t.Setenv("TESTAPP_CONFIG", path)
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		fs.SetConfigEnvVar("TESTAPP_CONFIG")
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *port != 9090 || fs.Lookup("port").Source() != "config" {
+			t.Errorf("Expected port 9090 from config, got %d", *port)
+		}
+	})
+
+	t.Run("config flag takes precedence", func(t *testing.T) {
+		other := createTempConfigFile(t, `{"port": 7070}`, "test-config-flag-*.json")
+		defer func() { _ = os.Remove(other) }()
+
+		t.Setenv("TESTAPP_CONFIG", path)
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		fs.EnableConfigFlag("config")
+		fs.SetConfigEnvVar("TESTAPP_CONFIG")
+
+		if err := fs.Parse([]string{"--config", other}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if *port != 7070 {
+			t.Errorf("Expected port 7070 from --config, got %d", *port)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("TESTAPP_CONFIG", "/tmp/does-not-exist-flashflags.json")
+		fs := New("test")
+		fs.SetConfigEnvVar("TESTAPP_CONFIG")
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: config file not found: /tmp/does-not-exist-flashflags.json (from TESTAPP_CONFIG)", "Expected missing file error")
+	})
+}
+
+// TestSetUsageHook tests the usage hook for CLI and environment sets
+func TestSetUsageHook(t *testing.T) {
+	t.Setenv("HOOKAPP_HOST", "example.com")
+
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	fs.IntVar("port", "p", 8080, "Port")
+	fs.BoolVar("verbose", "v", false, "Verbose")
+	fs.String("unused", "", "Unused")
+	fs.SetEnvPrefix("HOOKAPP")
+
+	var calls []string
+	fs.SetUsageHook(func(name, source string) {
+		calls = append(calls, name+"/"+source)
+	})
+
+	if err := fs.Parse([]string{"-p", "9090", "-v"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []string{"host/env", "port/cli", "verbose/cli"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected hook calls %v, got %v", want, calls)
+	}
+
+	t.Run("defaults file and bootstrap flags", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"host": "defaults.example.com"}`, "hook-defaults-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		fs.String("host", "localhost", "Host")
+		fs.String("profile", "dev", "Profile")
+		_ = fs.SetBootstrapFlag("profile")
+		fs.SetDefaultsFile(path)
+
+		var calls []string
+		fs.SetUsageHook(func(name, source string) {
+			calls = append(calls, name+"/"+source)
+		})
+		if err := fs.Parse([]string{"--profile", "prod"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		want := []string{"host/defaults", "profile/cli"}
+		if strings.Join(calls, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected hook calls %v, got %v", want, calls)
+		}
+	})
+}
+
+// TestPathValidators tests SetPathMustExist and SetPathMustBeDir
+func TestPathValidators(t *testing.T) {
+	dir := t.TempDir()
+	file := createTempConfigFile(t, "data", "test-path-*.txt")
+	defer func() { _ = os.Remove(file) }()
+	missing := dir + "/missing.txt"
+
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.String("input", "", "Input file")
+		fs.String("data-dir", "", "Data directory")
+		fs.Int("port", 0, "Port")
+		if err := fs.SetPathMustExist("input"); err != nil {
+			t.Fatalf("SetPathMustExist failed: %v", err)
+		}
+		if err := fs.SetPathMustBeDir("data-dir"); err != nil {
+			t.Fatalf("SetPathMustBeDir failed: %v", err)
+		}
+		return fs
+	}
+
+	t.Run("existing paths", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.Parse([]string{"--input", file, "--data-dir", dir}); err != nil {
+			t.Errorf("Expected existing file and dir to pass, got %v", err)
+		}
+	})
+
+	t.Run("unset is allowed", func(t *testing.T) {
+		if err := newSet().Parse([]string{}); err != nil {
+			t.Errorf("Expected empty paths to pass, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := newSet().Parse([]string{"--input", missing})
+		verifyExpectedError(t, err, "validation failed for flag --input: path does not exist: "+missing, "Expected missing path error")
+	})
+
+	t.Run("file instead of dir", func(t *testing.T) {
+		err := newSet().Parse([]string{"--data-dir", file})
+		verifyExpectedError(t, err, "validation failed for flag --data-dir: path is not a directory: "+file, "Expected dir mismatch error")
+	})
+
+	t.Run("setup errors", func(t *testing.T) {
+		fs := newSet()
+		verifyExpectedError(t, fs.SetPathMustExist("port"), "flag --port is not a string flag", "Expected type error")
+		verifyExpectedError(t, fs.SetPathMustBeDir("missing"), "flag not found: missing", "Expected not found error")
+	})
+}
+
+// TestCommit tests that values are read-only after Commit
+func TestCommit(t *testing.T) {
+	fs := New("test")
+	port := fs.Int("port", 8080, "Port")
+
+	if err := fs.Set("port", "9090"); err != nil || *port != 9090 {
+		t.Fatalf("Expected Set to work before Commit, got %d (%v)", *port, err)
+	}
+	verifyExpectedError(t, fs.Set("missing", "1"), "unknown flag: --missing", "Expected unknown flag error")
+
+	fs.Commit()
+
+	if err := fs.Set("port", "1"); !errors.Is(err, ErrCommitted) {
+		t.Errorf("Expected ErrCommitted from Set, got %v", err)
+	}
+	if err := fs.ResetFlag("port"); !errors.Is(err, ErrCommitted) {
+		t.Errorf("Expected ErrCommitted from ResetFlag, got %v", err)
+	}
+	if err := fs.Parse([]string{"--port", "1"}); !errors.Is(err, ErrCommitted) {
+		t.Errorf("Expected ErrCommitted from Parse, got %v", err)
+	}
+	t.Setenv("COMMIT_PORT", "7070")
+	fs.SetEnvPrefix("COMMIT")
+	if err := fs.LoadEnvironmentVariables(); !errors.Is(err, ErrCommitted) {
+		t.Errorf("Expected ErrCommitted from LoadEnvironmentVariables, got %v", err)
+	}
+	configFile := createTempConfigFile(t, `{"port": 6060}`, "commit-*.json")
+	defer func() { _ = os.Remove(configFile) }()
+	fs.SetConfigFile(configFile)
+	if err := fs.LoadConfig(); !errors.Is(err, ErrCommitted) {
+		t.Errorf("Expected ErrCommitted from LoadConfig, got %v", err)
+	}
+	fs.Reset()
+	fs.ResetAll()
+	if *port != 9090 || fs.GetInt("port") != 9090 {
+		t.Errorf("Expected value unchanged after Commit, got %d", *port)
+	}
+}
+
+// TestCountFlagsDoNotAccumulate tests that counts restart on each Parse and replace env values
+func TestCountFlagsDoNotAccumulate(t *testing.T) {
+	fs := New("test")
+	verbose := fs.CountVar("verbose", "v", "Verbosity")
+
+	if err := fs.Parse([]string{"-vv"}); err != nil || *verbose != 2 {
+		t.Fatalf("Expected 2, got %d (%v)", *verbose, err)
+	}
+	if err := fs.Parse([]string{"-v"}); err != nil || *verbose != 1 {
+		t.Errorf("Expected second Parse to count from zero, got %d (%v)", *verbose, err)
+	}
+	if err := fs.Parse([]string{"--verbose=5", "-v"}); err != nil || *verbose != 6 {
+		t.Errorf("Expected explicit value to be incremented, got %d (%v)", *verbose, err)
+	}
+
+	t.Setenv("COUNTACC_VERBOSE", "3")
+	fs = New("test")
+	verbose = fs.CountVar("verbose", "v", "Verbosity")
+	fs.SetEnvPrefix("COUNTACC")
+	if err := fs.Parse(nil); err != nil || *verbose != 3 {
+		t.Fatalf("Expected env count 3, got %d (%v)", *verbose, err)
+	}
+	if err := fs.Parse([]string{"-vv"}); err != nil || *verbose != 2 {
+		t.Errorf("Expected CLI count to replace env count, got %d (%v)", *verbose, err)
+	}
+}
+
+// TestCountFlagsInCombinedSequences tests counter flags mixed with booleans in -vvd style sequences
+func TestCountFlagsInCombinedSequences(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantCount int
+		wantDebug bool
+	}{
+		{[]string{"-vvd"}, 2, true},
+		{[]string{"-dvv"}, 2, true},
+		{[]string{"-vdv"}, 2, true},
+		{[]string{"-vvv"}, 3, false},
+		{[]string{"-v", "--verbose", "-v"}, 3, false},
+		{[]string{"--verbose=5"}, 5, false},
+		{[]string{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			fs := New("test")
+			verbose := fs.CountVar("verbose", "v", "Verbosity")
+			debug := fs.BoolVar("debug", "d", false, "Debug")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if *verbose != tt.wantCount || fs.GetInt("verbose") != tt.wantCount || *debug != tt.wantDebug {
+				t.Errorf("Expected verbose=%d debug=%v, got verbose=%d debug=%v", tt.wantCount, tt.wantDebug, *verbose, *debug)
+			}
+		})
+	}
+
+	fs := New("test")
+	fs.CountVar("verbose", "v", "Verbosity")
+	fs.IntVar("port", "p", 0, "Port")
+	if err := fs.Parse([]string{"-vp", "9090"}); err != nil || fs.GetInt("verbose") != 1 || fs.GetInt("port") != 9090 {
+		t.Errorf("Expected counter before a value flag to work, got verbose=%d port=%d (%v)", fs.GetInt("verbose"), fs.GetInt("port"), err)
+	}
+	if help := fs.Help(); strings.Contains(help, "--verbose INT") {
+		t.Errorf("Expected no type label for counter flags, got:\n%s", help)
+	}
+}
+
+// TestSetNoRepeat tests rejecting repeated scalar flags on the command line
+func TestSetNoRepeat(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.IntVar("port", "p", 8080, "Port")
+		fs.BoolVar("debug", "d", false, "Debug")
+		fs.StringSlice("tags", nil, "Tags")
+		_ = fs.SetNoRepeat("port")
+		_ = fs.SetNoRepeat("debug")
+		return fs
+	}
+
+	t.Run("repeated scalar", func(t *testing.T) {
+		err := newSet().Parse([]string{"--port", "80", "--port", "90"})
+		verifyExpectedError(t, err, "flag --port specified more than once", "Expected repeat error")
+
+		err = newSet().Parse([]string{"-p", "80", "--port=90"})
+		verifyExpectedError(t, err, "flag --port specified more than once", "Expected repeat error across syntaxes")
+
+		err = newSet().Parse([]string{"-d", "-d"})
+		verifyExpectedError(t, err, "flag --debug specified more than once", "Expected repeat error for bool")
+	})
+
+	t.Run("single occurrence per parse", func(t *testing.T) {
+		t.Setenv("PORT", "70")
+		fs := newSet()
+		fs.EnableEnvLookup()
+		if err := fs.Parse([]string{"--port", "80", "-d"}); err != nil {
+			t.Fatalf("Expected env plus one CLI occurrence to pass, got %v", err)
+		}
+		if err := fs.Parse([]string{"--port", "90"}); err != nil || fs.GetInt("port") != 90 {
+			t.Errorf("Expected a new parse to start fresh, got %v", err)
+		}
+	})
+
+	t.Run("repeated slice", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.Parse([]string{"--tags", "a", "--tags", "b,c"}); err != nil {
+			t.Errorf("Expected repeated slice flag to be allowed, got %v", err)
+		}
+		verifyExpectedError(t, fs.SetNoRepeat("tags"), "flag --tags is not a scalar flag", "Expected slice rejection")
+	})
+}
+
+// TestDurationHelpDefaults tests that help shows canonical duration defaults
+func TestDurationHelpDefaults(t *testing.T) {
+	tests := []struct {
+		value time.Duration
+		want  string
+	}{
+		{1500 * time.Millisecond, "1.5s"},
+		{250 * time.Millisecond, "250ms"},
+		{90 * time.Second, "1m30s"},
+		{2 * time.Hour, "2h0m0s"},
+		{time.Hour + 30*time.Minute + 45*time.Second, "1h30m45s"},
+		{1500 * time.Microsecond, "1.5ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			fs := New("test")
+			fs.Duration("timeout", tt.value, "Timeout")
+			if help := fs.Help(); !strings.Contains(help, "Timeout (default: "+tt.want+")") {
+				t.Errorf("Expected default %q in help, got:\n%s", tt.want, help)
+			}
+		})
+	}
+}
+
+// TestStringMap tests string map flags and merging across sources
+func TestStringMap(t *testing.T) {
+	configPath := createTempConfigFile(t, `{"label": {"app": "api", "env": "dev"}}`, "test-map-*.json")
+	defer func() { _ = os.Remove(configPath) }()
+
+	t.Run("merge config and CLI", func(t *testing.T) {
+		fs := New("test")
+		labels := fs.StringMap("label", nil, "Labels")
+		fs.SetConfigFile(configPath)
+		if err := fs.SetMapMerge("label", MapMerge); err != nil {
+			t.Fatalf("SetMapMerge failed: %v", err)
+		}
+
+		if err := fs.Parse([]string{"--label", "env=prod", "--label", "team=core"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		want := map[string]string{"app": "api", "env": "prod", "team": "core"}
+		if fmt.Sprint(*labels) != fmt.Sprint(want) || fmt.Sprint(fs.GetStringMap("label")) != fmt.Sprint(want) {
+			t.Errorf("Expected %v, got %v", want, *labels)
+		}
+	})
+
+	t.Run("replace by default", func(t *testing.T) {
+		fs := New("test")
+		labels := fs.StringMap("label", map[string]string{"app": "default"}, "Labels")
+		fs.SetConfigFile(configPath)
+
+		if err := fs.Parse([]string{"--label", "env=prod,team=core"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if len(*labels) != 2 || (*labels)["env"] != "prod" || (*labels)["team"] != "core" {
+			t.Errorf("Expected CLI map to replace config map, got %v", *labels)
+		}
+
+		fs.Reset()
+		if fmt.Sprint(*labels) != "map[app:default]" {
+			t.Errorf("Expected reset to default, got %v", *labels)
+		}
+		if !strings.Contains(fs.Help(), "--label KEY=VALUE") || !strings.Contains(fs.Help(), "(default: app=default)") {
+			t.Errorf("Unexpected help:\n%s", fs.Help())
+		}
+	})
+
+	t.Run("env JSON object merges", func(t *testing.T) {
+		t.Setenv("MAPAPP_LABEL", `{"region": "eu"}`)
+		fs := New("test")
+		labels := fs.StringMap("label", nil, "Labels")
+		fs.SetConfigFile(configPath)
+		fs.SetEnvPrefix("MAPAPP")
+		_ = fs.SetMapMerge("label", MapMerge)
+
+		if err := fs.Parse([]string{"--label", "env=prod"}); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if len(*labels) != 3 || (*labels)["region"] != "eu" || (*labels)["env"] != "prod" {
+			t.Errorf("Expected config, env, and CLI entries, got %v", *labels)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs := New("test")
+		fs.StringMap("label", nil, "Labels")
+		fs.Int("port", 0, "Port")
+		err := fs.Parse([]string{"--label", "novalue"})
+		verifyExpectedError(t, err, "invalid map entry for flag --label: novalue (expected key=value)", "Expected entry error")
+		verifyExpectedError(t, fs.SetMapMerge("port", MapMerge), "flag --port is not a string map flag", "Expected type error")
+	})
+}
+
+// TestStrictConfigTypes tests rejecting config type coercion in strict mode
+func TestStrictConfigTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"float for int", `{"workers": 4.5}`, "expected integer for flag workers, got 4.5"},
+		{"number for string", `{"host": 42}`, "expected string for flag host, got float64"},
+		{"string for bool", `{"debug": "true"}`, "expected boolean for flag debug, got string"},
+		{"string for int", `{"workers": "4"}`, "expected number for flag workers, got string"},
+		{"number in string slice", `{"tags": ["a", 1]}`, "expected string array for flag tags, got float64 in array"},
+		{"bool in string map", `{"labels": {"a": true}}`, "expected string values for flag labels, got bool for key a"},
+		{"string for uint64", `{"limit": "18446744073709551615"}`, "expected number for flag limit, got string"},
+		{"string for float", `{"ratio": "0.5"}`, "expected number for flag ratio, got string"},
+		{"plain number string for bytes", `{"buffer": "1024"}`, `expected number or size with unit for flag buffer, got string "1024"`},
+		{"number for duration", `{"timeout": 30}`, "expected duration string for flag timeout, got float64"},
+		{"string for int slice", `{"ports": "80,443"}`, "expected array for flag ports, got string"},
+		{"string for struct slice", `{"routes": "path=/a"}`, "expected array for flag routes, got string"},
+		{"number for custom", `{"level": 3}`, "expected string for flag level, got float64"},
+	}
+
+	newSet := func(strict bool) *FlagSet {
+		fs := New("test")
+		fs.Int("workers", 1, "Workers")
+		fs.String("host", "", "Host")
+		fs.Bool("debug", false, "Debug")
+		fs.StringSlice("tags", nil, "Tags")
+		fs.StringMap("labels", nil, "Labels")
+		fs.Uint64("limit", 0, "Limit")
+		fs.Float64("ratio", 0, "Ratio")
+		fs.Bytes("buffer", 0, "Buffer size")
+		fs.Duration("timeout", time.Second, "Timeout")
+		_ = fs.SetDurationDefaultUnit("timeout", time.Second)
+		fs.IntSlice("ports", nil, "Ports")
+		fs.StructSlice("routes", func(s string) (interface{}, error) { return s, nil }, "Routes")
+		fs.Custom("level", "info", func(s string) (interface{}, error) { return s, nil }, "Level")
+		fs.SetCoerceConfigSlices(true)
+		fs.SetStrictConfigTypes(strict)
+		return fs
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTempConfigFile(t, tt.config, "test-strict-*.json")
+			defer func() { _ = os.Remove(path) }()
+
+			fs := newSet(true)
+			fs.SetConfigFile(path)
+			err := fs.Parse([]string{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"workers": 4.5, "tags": ["a", 1], "labels": {"n": 2}}`, "test-lenient-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(false)
+		fs.SetConfigFile(path)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Expected lenient coercion, got %v", err)
+		}
+		if fs.GetInt("workers") != 4 || fs.GetStringSlice("tags")[1] != "1" || fs.GetStringMap("labels")["n"] != "2" {
+			t.Errorf("Unexpected coerced values: %v %v %v", fs.GetInt("workers"), fs.GetStringSlice("tags"), fs.GetStringMap("labels"))
+		}
+	})
+
+	t.Run("lenient coercions for each type", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"limit": "7", "buffer": "1024", "timeout": 30, "ports": "80,443", "routes": "/a", "level": 3}`, "test-lenient-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(false)
+		fs.SetConfigFile(path)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Expected lenient coercion, got %v", err)
+		}
+		if fs.GetUint64("limit") != 7 || fs.GetBytes("buffer") != 1024 || fs.GetDuration("timeout") != 30*time.Second ||
+			len(fs.GetIntSlice("ports")) != 2 || fs.Lookup("level").Value() != "3" {
+			t.Errorf("Unexpected coerced values: %v %v %v %v %v", fs.GetUint64("limit"), fs.GetBytes("buffer"),
+				fs.GetDuration("timeout"), fs.GetIntSlice("ports"), fs.Lookup("level").Value())
+		}
+	})
+
+	t.Run("strings rejected with trimming in strict mode", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"workers": " 4 "}`, "test-strict-trim-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(true)
+		fs.SetTrimValues(true)
+		fs.SetConfigFile(path)
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: failed to set flag workers from config: expected number for flag workers, got string",
+			"Expected trimming not to bypass strict types")
+	})
+
+	t.Run("native forms accepted in strict mode", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"limit": 7, "buffer": "10KB", "timeout": "1m", "ports": [80], "routes": ["/a"], "level": "debug"}`, "test-strict-ok-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(true)
+		fs.SetConfigFile(path)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Expected native config types to be accepted, got %v", err)
+		}
+		if fs.GetUint64("limit") != 7 || fs.GetBytes("buffer") != 10*1024 || fs.GetDuration("timeout") != time.Minute {
+			t.Errorf("Unexpected values: %v %v %v", fs.GetUint64("limit"), fs.GetBytes("buffer"), fs.GetDuration("timeout"))
+		}
+	})
+
+	t.Run("integral number for int in strict mode", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"workers": 8}`, "test-strict-ok-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(true)
+		fs.SetConfigFile(path)
+		if err := fs.Parse([]string{}); err != nil || fs.GetInt("workers") != 8 {
+			t.Errorf("Expected integral JSON number to be accepted, got %v", err)
+		}
+	})
+}
+
+// TestResetConfigState tests re-reading config after resetting the config state
+func TestResetConfigState(t *testing.T) {
+	configA := createTempConfigFile(t, `{"port": 1111}`, "test-config-a-*.json")
+	configB := createTempConfigFile(t, `{"port": 2222}`, "test-config-b-*.json")
+	defer func() { _ = os.Remove(configA) }()
+	defer func() { _ = os.Remove(configB) }()
+
+	fs := New("test")
+	port := fs.Int("port", 8080, "Port")
+	fs.SetConfigFile(configA)
+	if err := fs.Parse([]string{}); err != nil || *port != 1111 {
+		t.Fatalf("Expected config A to load, got %d (%v)", *port, err)
+	}
+
+	// Without a reset the config is not read again
+	fs.SetConfigFile(configB)
+	if err := fs.Parse([]string{}); err != nil || *port != 1111 {
+		t.Fatalf("Expected stale config A value, got %d (%v)", *port, err)
+	}
+
+	fs.ResetConfigState()
+	if err := fs.Parse([]string{}); err != nil || *port != 2222 {
+		t.Errorf("Expected config B to load after ResetConfigState, got %d (%v)", *port, err)
+	}
+
+	fs.SetConfigFile(configA)
+	fs.Reset()
+	if err := fs.Parse([]string{}); err != nil || *port != 1111 {
+		t.Errorf("Expected Reset to clear the config state too, got %d (%v)", *port, err)
+	}
+
+	fs.Commit()
+	fs.ResetConfigState()
+	if *port != 1111 || fs.Lookup("port").Source() != "config" {
+		t.Errorf("Expected config value kept after Commit, got %d (%s)", *port, fs.Lookup("port").Source())
+	}
+}
+
+// TestConfigLimits tests rejecting deeply nested and oversized config files
+func TestConfigLimits(t *testing.T) {
+	t.Run("deeply nested JSON", func(t *testing.T) {
+		nested := `{"port": ` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `}`
+		path := createTempConfigFile(t, nested, "test-deep-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		fs.Int("port", 8080, "Port")
+		fs.SetConfigFile(path)
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: config file "+path+" exceeds maximum nesting depth of 32", "Expected depth error")
+	})
+
+	t.Run("brackets inside strings are ignored", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"name": "[[[[{{{{\"]]]]"}`, "test-brackets-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		fs.String("name", "", "Name")
+		fs.SetConfigFile(path)
+		fs.SetConfigLimits(2, 0)
+		if err := fs.Parse([]string{}); err != nil {
+			t.Errorf("Expected string content not to count as nesting, got %v", err)
+		}
+	})
+
+	t.Run("oversized file", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"name": "`+strings.Repeat("x", 2048)+`"}`, "test-large-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		fs.String("name", "", "Name")
+		fs.SetConfigFile(path)
+		fs.SetConfigLimits(0, 1024)
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: config file "+path+" exceeds maximum size of 1024 bytes", "Expected size error")
+	})
+}
+
+// TestSetConfigAllowedFlags tests restricting which flags a config file may set
+func TestSetConfigAllowedFlags(t *testing.T) {
+	newSet := func(path string) (*FlagSet, *int, *bool) {
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		admin := fs.Bool("admin-mode", false, "Admin mode")
+		fs.SetConfigFile(path)
+		fs.SetConfigAllowedFlags("port")
+		return fs, port, admin
+	}
+
+	t.Run("allowed flags apply", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"port": 9090, "unknown": 1}`, "test-allow-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs, port, _ := newSet(path)
+		if err := fs.Parse([]string{}); err != nil || *port != 9090 {
+			t.Errorf("Expected allowed flag to apply, got %d (%v)", *port, err)
+		}
+	})
+
+	t.Run("disallowed flag is rejected", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"port": 9090, "admin-mode": true}`, "test-deny-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs, _, admin := newSet(path)
+		err := fs.Parse([]string{})
+		verifyExpectedError(t, err, "config file error: config file may not set flag --admin-mode", "Expected disallowed flag error")
+		if *admin {
+			t.Error("Expected admin-mode to stay unset")
+		}
+
+		// The command line can still set it
+		fs2, _, admin2 := newSet("")
+		if err := fs2.Parse([]string{"--admin-mode"}); err != nil || !*admin2 {
+			t.Errorf("Expected CLI to set admin-mode, got %v", err)
+		}
+	})
+}
+
+// TestSetPostParse tests the callback run after a successful parse
+func TestSetPostParse(t *testing.T) {
+	fs := New("test")
+	fs.Int("port", 8080, "Port")
+	_ = fs.SetIntRange("port", 1, 65535)
+
+	calls := 0
+	var seenPort int
+	fs.SetPostParse(func(fs *FlagSet) error {
+		calls++
+		seenPort = fs.GetInt("port")
+		if seenPort == 1 {
+			return errors.New("port 1 is reserved")
+		}
+		return nil
+	})
+
+	if err := fs.Parse([]string{"--port", "9090"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if calls != 1 || seenPort != 9090 {
+		t.Errorf("Expected one call seeing port 9090, got %d calls, port %d", calls, seenPort)
+	}
+
+	err := fs.Parse([]string{"--port", "1"})
+	verifyExpectedError(t, err, "port 1 is reserved", "Expected callback error to abort Parse")
+
+	calls = 0
+	if err := fs.Parse([]string{"--port", "70000"}); err == nil {
+		t.Error("Expected range error")
+	}
+	if calls != 0 {
+		t.Errorf("Expected callback not to run when constraints fail, got %d calls", calls)
+	}
+}
+
+// TestEmptyNameFallsBackToProgramName tests help for a FlagSet created with an empty name
+func TestEmptyNameFallsBackToProgramName(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+	os.Args = []string{"/usr/local/bin/mytool", "--port", "1"}
+
+	fs := New("")
+	fs.Int("port", 8080, "Port")
+	if help := fs.Help(); !strings.Contains(help, "Usage: mytool [options]") {
+		t.Errorf("Expected program name from os.Args[0], got:\n%s", help)
+	}
+
+	fs.SetName("explicit")
+	if help := fs.Help(); !strings.Contains(help, "Usage: explicit [options]") {
+		t.Errorf("Expected explicit name to win, got:\n%s", help)
+	}
+}
+
+// TestSetHelpSections tests custom help section order
+func TestSetHelpSections(t *testing.T) {
+	fs := New("myapp")
+	fs.SetDescription("My application")
+	fs.SetVersion("1.2.3")
+	fs.String("host", "localhost", "Server host")
+	fs.SetExamples("myapp --host example.com")
+
+	t.Run("default order", func(t *testing.T) {
+		help := fs.Help()
+		if !strings.Contains(help, "Version: 1.2.3") {
+			t.Errorf("Expected version in default help, got:\n%s", help)
+		}
+		if strings.Index(help, "Options:") > strings.Index(help, "Examples:") {
+			t.Errorf("Expected options before examples, got:\n%s", help)
+		}
+	})
+
+	t.Run("custom order", func(t *testing.T) {
+		fs.SetHelpSections(SectionUsage, SectionExamples, SectionOptions)
+		defer fs.SetHelpSections()
+
+		help := fs.Help()
+		expected := "Usage: myapp [options]\n\nExamples:\n  myapp --host example.com\n\nOptions:\n"
+		if !strings.HasPrefix(help, expected) {
+			t.Errorf("Expected help to start with %q, got:\n%s", expected, help)
+		}
+		if strings.Contains(help, "Version") || strings.Contains(help, "My application") {
+			t.Errorf("Expected omitted sections to be hidden, got:\n%s", help)
+		}
+	})
+}
+
+// TestFlagHelp tests single-flag help rendering
+func TestFlagHelp(t *testing.T) {
+	fs := New("test")
+	fs.Int("port", 8080, "Server port")
+	fs.String("host", "localhost", "Server host")
+
+	line, err := fs.FlagHelp("port")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(line, "--port") || !strings.HasSuffix(line, "\n") {
+		t.Errorf("Unexpected flag help line: %q", line)
+	}
+	if !strings.Contains(fs.Help(), line) {
+		t.Errorf("Expected Help() to contain %q", line)
+	}
+
+	_, err = fs.FlagHelp("missing")
+	verifyExpectedError(t, err, "flag not found: missing", "FlagHelp")
+}
+
+// TestSetEnvFallbackUnprefixed tests unprefixed environment variable fallback
+func TestSetEnvFallbackUnprefixed(t *testing.T) {
+	t.Run("unprefixed used as fallback", func(t *testing.T) {
+		t.Setenv("DB_HOST", "legacy.example.com")
+
+		fs := New("test")
+		host := fs.String("db-host", "localhost", "Database host")
+		fs.SetEnvPrefix("MYAPP")
+		fs.SetEnvFallbackUnprefixed(true)
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *host != "legacy.example.com" {
+			t.Errorf("Expected 'legacy.example.com', got '%s'", *host)
+		}
+	})
+
+	t.Run("prefixed wins", func(t *testing.T) {
+		t.Setenv("DB_HOST", "legacy.example.com")
+		t.Setenv("MYAPP_DB_HOST", "new.example.com")
+
+		fs := New("test")
+		host := fs.String("db-host", "localhost", "Database host")
+		fs.SetEnvPrefix("MYAPP")
+		fs.SetEnvFallbackUnprefixed(true)
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *host != "new.example.com" {
+			t.Errorf("Expected 'new.example.com', got '%s'", *host)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Setenv("DB_HOST", "legacy.example.com")
+
+		fs := New("test")
+		host := fs.String("db-host", "localhost", "Database host")
+		fs.SetEnvPrefix("MYAPP")
+
+		if err := fs.Parse([]string{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *host != "localhost" {
+			t.Errorf("Expected 'localhost', got '%s'", *host)
+		}
+	})
+}
+
+// TestEmptyEqualsValue tests --flag= semantics for each flag type
+func TestEmptyEqualsValue(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.String("name", "default", "Name")
+		fs.StringSlice("tags", []string{"a", "b"}, "Tags")
+		fs.StringMap("labels", map[string]string{"env": "prod"}, "Labels")
+		fs.Int("count", 1, "Count")
+		fs.Float64("ratio", 0.5, "Ratio")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.Bool("verbose", true, "Verbose")
+		return fs
+	}
+
+	t.Run("string clears", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.Parse([]string{"--name="}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := fs.GetString("name"); got != "" {
+			t.Errorf("Expected empty string, got '%s'", got)
+		}
+	})
+
+	t.Run("slice clears", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.Parse([]string{"--tags="}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := fs.GetStringSlice("tags"); len(got) != 0 {
+			t.Errorf("Expected empty slice, got %v", got)
+		}
+	})
+
+	t.Run("map clears", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.Parse([]string{"--labels="}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := fs.GetStringMap("labels"); len(got) != 0 {
+			t.Errorf("Expected empty map, got %v", got)
+		}
+	})
+
+	for _, name := range []string{"count", "ratio", "timeout", "verbose"} {
+		t.Run(name+" rejects", func(t *testing.T) {
+			fs := newSet()
+			err := fs.Parse([]string{"--" + name + "="})
+			verifyExpectedError(t, err, "flag --"+name+" requires a non-empty value", "Expected empty value to be rejected")
+		})
+	}
+}
+
+// TestDurationVarAndFloat64Var tests duration and float64 flags with short keys
+func TestDurationVarAndFloat64Var(t *testing.T) {
+	fs := New("test")
+	timeout := fs.DurationVar("timeout", "t", 30*time.Second, "Request timeout")
+	ratio := fs.Float64Var("ratio", "r", 1.0, "Sampling ratio")
+
+	if err := fs.Parse([]string{"-t", "45s", "-r", "2.5"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *timeout != 45*time.Second {
+		t.Errorf("Expected timeout 45s, got %v", *timeout)
+	}
+	if *ratio != 2.5 {
+		t.Errorf("Expected ratio 2.5, got %v", *ratio)
+	}
+	if flag := fs.Lookup("timeout"); flag.ShortKey() != "t" {
+		t.Errorf("Expected short key 't', got '%s'", flag.ShortKey())
+	}
+}
+
+// TestStringSliceVar tests string slice flags with a short key
+func TestStringSliceVar(t *testing.T) {
+	fs := New("test")
+	tags := fs.StringSliceVar("tags", "t", []string{"default"}, "Service tags")
+
+	if err := fs.Parse([]string{"-t", "web,api"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(*tags) != 2 || (*tags)[0] != "web" || (*tags)[1] != "api" {
+		t.Errorf("Expected [web api], got %v", *tags)
+	}
+	if !strings.Contains(fs.Help(), "-t, --tags") {
+		t.Errorf("Expected short key in help, got:\n%s", fs.Help())
+	}
+}
+
+// TestParseFileAndArgs tests loading CLI flags from a file
+func TestParseFileAndArgs(t *testing.T) {
+	path := createTempConfigFile(t, "# defaults\n--host file.example.com\n--port 8080\n\n--name=hello world\n--verbose\n", "flags-*.txt")
+	defer func() { _ = os.Remove(path) }()
+
+	fs := New("test")
+	host := fs.String("host", "localhost", "Server host")
+	port := fs.Int("port", 80, "Server port")
+	name := fs.String("name", "", "Name")
+	verbose := fs.Bool("verbose", false, "Verbose output")
+
+	if err := fs.ParseFileAndArgs(path, []string{"--port", "9090", "input.txt"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *host != "file.example.com" {
+		t.Errorf("Expected host from file, got '%s'", *host)
+	}
+	if *port != 9090 {
+		t.Errorf("Expected explicit port 9090 to override the file, got %d", *port)
+	}
+	if *name != "hello world" {
+		t.Errorf("Expected 'hello world', got '%s'", *name)
+	}
+	if !*verbose {
+		t.Error("Expected verbose from file")
+	}
+	if args := fs.Args(); len(args) != 1 || args[0] != "input.txt" {
+		t.Errorf("Expected [input.txt], got %v", args)
+	}
+
+	t.Run("assign char inside a spaced value", func(t *testing.T) {
+		path := createTempConfigFile(t, "--filter status=active\n--query\ta=b c=d\n", "flags-*.txt")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		filter := fs.String("filter", "", "Filter")
+		query := fs.String("query", "", "Query")
+		if err := fs.ParseFileAndArgs(path, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *filter != "status=active" || *query != "a=b c=d" {
+			t.Errorf("Expected values after the first space, got '%s' and '%s'", *filter, *query)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fs := New("test")
+		if err := fs.ParseFileAndArgs("/nonexistent/flags.txt", nil); err == nil {
+			t.Error("Expected error for missing flags file")
+		}
+	})
+}
+
+// TestParseAllocs tests that a simple parse stays within a small allocation budget
+func TestParseAllocs(t *testing.T) {
+	fs := New("test")
+	port := fs.IntVar("port", "p", 80, "Server port")
+	fs.BoolVar("verbose", "v", false, "Verbose output")
+	fs.String("host", "localhost", "Server host")
+	args := []string{"--port", "8080", "-v", "--host=example.com"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	if allocs > 10 {
+		t.Errorf("Expected a simple parse to stay under 10 allocations, got %v", allocs)
+	}
+	if *port != 8080 {
+		t.Errorf("Expected port 8080, got %d", *port)
+	}
+}
+
+// TestWatchChanges tests change notifications from ReloadConfig
+func TestWatchChanges(t *testing.T) {
+	path := createTempConfigFile(t, `{"host": "a.example.com", "port": 8080, "debug": true}`, "reload-*.json")
+	defer func() { _ = os.Remove(path) }()
+
+	fs := New("test")
+	host := fs.String("host", "localhost", "Server host")
+	port := fs.Int("port", 80, "Server port")
+	debug := fs.Bool("debug", false, "Debug mode")
+	workers := fs.Int("workers", 1, "Worker count")
+	fs.SetConfigFile(path)
+
+	if err := fs.Parse([]string{"--workers", "4"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	changes := fs.WatchChanges()
+
+	if err := os.WriteFile(path, []byte(`{"host": "b.example.com", "port": 8080, "workers": 8}`), 0600); err != nil {
+		t.Fatalf("Failed to rewrite config: %v", err)
+	}
+	if err := fs.ReloadConfig(); err != nil {
+		t.Fatalf("Unexpected reload error: %v", err)
+	}
+
+	var events []ChangeEvent
+	for len(changes) > 0 {
+		events = append(events, <-changes)
+	}
+	expected := []ChangeEvent{
+		{Name: "debug", Old: true, New: false},
+		{Name: "host", Old: "a.example.com", New: "b.example.com"},
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+	if *host != "b.example.com" || *port != 8080 || *debug || *workers != 4 {
+		t.Errorf("Unexpected values after reload: host=%s port=%d debug=%t workers=%d", *host, *port, *debug, *workers)
+	}
+
+	t.Run("invalid config keeps values", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`{"host": `), 0600); err != nil {
+			t.Fatalf("Failed to rewrite config: %v", err)
+		}
+		if err := fs.ReloadConfig(); err == nil {
+			t.Error("Expected reload error for invalid config")
+		}
+		if *host != "b.example.com" || len(changes) != 0 {
+			t.Errorf("Expected values untouched, host=%s pending events=%d", *host, len(changes))
+		}
+	})
+
+	t.Run("committed set is not reloaded", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`{"host": "c.example.com"}`), 0600); err != nil {
+			t.Fatalf("Failed to rewrite config: %v", err)
+		}
+		fs.Commit()
+		if err := fs.ReloadConfig(); !errors.Is(err, ErrCommitted) {
+			t.Errorf("Expected ErrCommitted, got %v", err)
+		}
+		if *host != "b.example.com" || len(changes) != 0 {
+			t.Errorf("Expected values untouched, host=%s pending events=%d", *host, len(changes))
+		}
+	})
+}
+
+// TestUint64Flag tests native uint64 flags
+func TestUint64Flag(t *testing.T) {
+	t.Run("full range from CLI", func(t *testing.T) {
+		fs := New("test")
+		max := fs.Uint64Var("max-items", "n", 10, "Maximum items")
+		if err := fs.Parse([]string{"-n", "18446744073709551615"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *max != math.MaxUint64 || fs.GetUint64("max-items") != math.MaxUint64 {
+			t.Errorf("Expected %d, got %d", uint64(math.MaxUint64), *max)
+		}
+	})
+
+	t.Run("negative rejected", func(t *testing.T) {
+		fs := New("test")
+		fs.Uint64("limit", 1, "Limit")
+		err := fs.Parse([]string{"--limit=-1"})
+		verifyExpectedError(t, err, "invalid uint64 value for flag --limit: -1", "Expected negative value to be rejected")
+	})
+
+	t.Run("config number and string", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"limit": 42, "big": "18446744073709551615"}`, "uint-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		limit := fs.Uint64("limit", 1, "Limit")
+		big := fs.Uint64("big", 1, "Big")
+		fs.SetConfigFile(path)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *limit != 42 || *big != math.MaxUint64 {
+			t.Errorf("Expected 42 and max uint64, got %d and %d", *limit, *big)
+		}
+	})
+}
+
+// TestStructSlice tests repeatable structured flags
+func TestStructSlice(t *testing.T) {
+	type route struct {
+		Path string
+		Port int
+	}
+	parseRoute := func(s string) (interface{}, error) {
+		var r route
+		for _, field := range strings.Split(s, ",") {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "path":
+				r.Path = value
+			case "port":
+				port, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, err
+				}
+				r.Port = port
+			default:
+				return nil, fmt.Errorf("unknown field %q", key)
+			}
+		}
+		return r, nil
+	}
+
+	t.Run("repeated entries", func(t *testing.T) {
+		fs := New("test")
+		routes := fs.StructSlice("route", parseRoute, "Route definition")
+
+		if err := fs.Parse([]string{"--route", "path=/a,port=80", "--route=path=/b,port=90"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []interface{}{route{"/a", 80}, route{"/b", 90}}
+		if fmt.Sprint(*routes) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, *routes)
+		}
+	})
+
+	t.Run("CLI replaces config", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"route": ["path=/c,port=70", "path=/d,port=60"]}`, "routes-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		routes := fs.StructSlice("route", parseRoute, "Route definition")
+		fs.SetConfigFile(path)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*routes) != 2 {
+			t.Fatalf("Expected 2 routes from config, got %v", *routes)
+		}
+
+		fs2 := New("test")
+		routes2 := fs2.StructSlice("route", parseRoute, "Route definition")
+		fs2.SetConfigFile(path)
+		if err := fs2.Parse([]string{"--route", "path=/a,port=80"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fmt.Sprint(*routes2) != fmt.Sprint([]interface{}{route{"/a", 80}}) {
+			t.Errorf("Expected CLI route only, got %v", *routes2)
+		}
+	})
+
+	t.Run("invalid element", func(t *testing.T) {
+		fs := New("test")
+		fs.StructSlice("route", parseRoute, "Route definition")
+		err := fs.Parse([]string{"--route", "path=/a,port=x"})
+		if err == nil || !strings.Contains(err.Error(), "invalid value for flag --route") {
+			t.Errorf("Expected parse error, got %v", err)
+		}
+	})
+}
+
+// TestSetSecretsDir tests reading secret flags from a secrets directory
+func TestSetSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/db-password", []byte("s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	if err := os.WriteFile(dir+"/api-token", []byte("not-a-secret-flag"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	newSet := func() (*FlagSet, *string, *string) {
+		fs := New("test")
+		password := fs.String("db-password", "", "Database password")
+		token := fs.String("api-token", "", "API token")
+		if err := fs.SetSecret("db-password"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		fs.SetSecretsDir(dir)
+		return fs, password, token
+	}
+
+	t.Run("read from file", func(t *testing.T) {
+		fs, password, token := newSet()
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *password != "s3cret" {
+			t.Errorf("Expected 's3cret', got '%s'", *password)
+		}
+		if *token != "" {
+			t.Errorf("Expected non-secret flag to ignore the secrets dir, got '%s'", *token)
+		}
+	})
+
+	t.Run("env var wins", func(t *testing.T) {
+		t.Setenv("MYAPP_DB_PASSWORD", "from-env")
+		fs, password, _ := newSet()
+		fs.SetEnvPrefix("MYAPP")
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *password != "from-env" {
+			t.Errorf("Expected 'from-env', got '%s'", *password)
+		}
+	})
+
+	t.Run("CLI wins", func(t *testing.T) {
+		fs, password, _ := newSet()
+		if err := fs.Parse([]string{"--db-password", "from-cli"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *password != "from-cli" {
+			t.Errorf("Expected 'from-cli', got '%s'", *password)
+		}
+	})
+}
+
+// TestUnknownShortFlagErrors tests the unified unknown short flag errors and suggestions
+func TestUnknownShortFlagErrors(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.BoolVar("version", "V", false, "Print version")
+		fs.BoolVar("debug", "d", false, "Debug mode")
+		fs.Duration("timeout", time.Second, "Request timeout")
+		return fs
+	}
+
+	tests := []struct {
+		args     []string
+		suggest  bool
+		expected string
+	}{
+		{[]string{"-x"}, false, "unknown flag: -x"},
+		{[]string{"-x=1"}, false, "unknown flag: -x"},
+		{[]string{"-dx"}, false, "unknown flag: -x in combined sequence -dx"},
+		{[]string{"-v"}, true, "unknown flag: -v (did you mean -V?)"},
+		{[]string{"-dv"}, true, "unknown flag: -v in combined sequence -dv (did you mean -V?)"},
+		{[]string{"-t=5s"}, true, "unknown flag: -t (did you mean --timeout?)"},
+		{[]string{"-x"}, true, "unknown flag: -x"},
+	}
+
+	for _, tt := range tests {
+		fs := newSet()
+		fs.SetSuggestShort(tt.suggest)
+		err := fs.Parse(tt.args)
+		verifyExpectedError(t, err, tt.expected, strings.Join(tt.args, " "))
+	}
+}
+
+// TestPeekEnv tests reading the environment value of an undefined flag
+func TestPeekEnv(t *testing.T) {
+	t.Setenv("MYAPP_STORAGE_BACKEND", "s3")
+	t.Setenv("LEGACY_REGION", "eu-west-1")
+	t.Setenv("BUCKET_NAME", "assets")
+
+	fs := New("test")
+	fs.SetEnvPrefix("MYAPP")
+	fs.AddEnvPrefix("LEGACY")
+
+	if value, ok := fs.PeekEnv("storage-backend"); !ok || value != "s3" {
+		t.Errorf("Expected 's3', got '%s' (set: %t)", value, ok)
+	}
+	if value, ok := fs.PeekEnv("region"); !ok || value != "eu-west-1" {
+		t.Errorf("Expected fallback prefix value 'eu-west-1', got '%s' (set: %t)", value, ok)
+	}
+	if _, ok := fs.PeekEnv("missing"); ok {
+		t.Error("Expected unset variable to be reported as not set")
+	}
+	if _, exists := fs.flags["storage-backend"]; exists {
+		t.Error("Expected PeekEnv not to define the flag")
+	}
+
+	fs.String("bucket", "", "Bucket")
+	if err := fs.SetEnvVar("bucket", "BUCKET_NAME"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, ok := fs.PeekEnv("bucket"); !ok || value != "assets" {
+		t.Errorf("Expected custom variable value 'assets', got '%s' (set: %t)", value, ok)
+	}
+}
+
+// TestSetTrimValues tests the whitespace trimming policy for scalar values
+func TestSetTrimValues(t *testing.T) {
+	newSet := func(trim bool) *FlagSet {
+		fs := New("test")
+		fs.Int("port", 80, "Port")
+		fs.Float64("ratio", 0.5, "Ratio")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.Bool("verbose", false, "Verbose")
+		fs.String("name", "", "Name")
+		fs.SetTrimValues(trim)
+		return fs
+	}
+	args := []string{"--port", " 8080 ", "--ratio= 2.5", "--timeout", "5s\t", "--verbose= true "}
+
+	t.Run("strict by default", func(t *testing.T) {
+		for i := 0; i < len(args); i++ {
+			fs := newSet(false)
+			arg := []string{args[i]}
+			if args[i] == "--port" || args[i] == "--timeout" {
+				arg = append(arg, args[i+1])
+				i++
+			}
+			if err := fs.Parse(arg); err == nil {
+				t.Errorf("Expected padded value %q to be rejected", arg)
+			}
+		}
+	})
+
+	t.Run("trimmed CLI values", func(t *testing.T) {
+		fs := newSet(true)
+		if err := fs.Parse(append(args, "--name", " padded ")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetInt("port") != 8080 || fs.GetFloat64("ratio") != 2.5 ||
+			fs.GetDuration("timeout") != 5*time.Second || !fs.GetBool("verbose") {
+			t.Errorf("Unexpected values: port=%d ratio=%v timeout=%v verbose=%t",
+				fs.GetInt("port"), fs.GetFloat64("ratio"), fs.GetDuration("timeout"), fs.GetBool("verbose"))
+		}
+		if fs.GetString("name") != " padded " {
+			t.Errorf("Expected string values to keep whitespace, got %q", fs.GetString("name"))
+		}
+	})
+
+	t.Run("int slices always tolerate whitespace", func(t *testing.T) {
+		fs := newSet(false)
+		ports := fs.IntSlice("ports", nil, "Ports")
+		if err := fs.Parse([]string{"--ports", " 80, 443 "}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*ports) != 2 || (*ports)[0] != 80 || (*ports)[1] != 443 {
+			t.Errorf("Expected [80 443], got %v", *ports)
+		}
+	})
+
+	t.Run("bytes follow the same policy", func(t *testing.T) {
+		fs := newSet(false)
+		fs.Bytes("max-body", 0, "Max body")
+		err := fs.Parse([]string{"--max-body", " 10MB "})
+		verifyExpectedError(t, err, "invalid bytes value for flag --max-body:  10MB ", "Expected padded size to be rejected")
+
+		fs.SetTrimValues(true)
+		if err := fs.Parse([]string{"--max-body", " 10MB "}); err != nil || fs.GetBytes("max-body") != 10*1024*1024 {
+			t.Errorf("Expected trimmed size, got %d (err: %v)", fs.GetBytes("max-body"), err)
+		}
+	})
+
+	t.Run("trimmed env and config values", func(t *testing.T) {
+		t.Setenv("TRIM_PORT", " 9090 ")
+		path := createTempConfigFile(t, `{"ratio": " 1.5 ", "timeout": " 2m ", "verbose": "true "}`, "trim-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := newSet(true)
+		fs.SetEnvPrefix("TRIM")
+		fs.SetConfigFile(path)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetInt("port") != 9090 || fs.GetFloat64("ratio") != 1.5 ||
+			fs.GetDuration("timeout") != 2*time.Minute || !fs.GetBool("verbose") {
+			t.Errorf("Unexpected values: port=%d ratio=%v timeout=%v verbose=%t",
+				fs.GetInt("port"), fs.GetFloat64("ratio"), fs.GetDuration("timeout"), fs.GetBool("verbose"))
+		}
+
+		strict := newSet(false)
+		strict.SetEnvPrefix("TRIM")
+		if err := strict.Parse(nil); err == nil {
+			t.Error("Expected padded env value to be rejected in strict mode")
+		}
+	})
+}
+
+// TestConfigDurationValues tests duration flags from config strings and numbers
+func TestConfigDurationValues(t *testing.T) {
+	newSet := func(content string) *FlagSet {
+		path := createTempConfigFile(t, content, "duration-*.json")
+		t.Cleanup(func() { _ = os.Remove(path) })
+		fs := New("test")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.Duration("interval", time.Second, "Interval")
+		fs.Duration("delay", time.Second, "Delay")
+		_ = fs.SetDurationDefaultUnit("interval", time.Second)
+		_ = fs.SetDurationDefaultUnit("delay", time.Minute)
+		fs.SetConfigFile(path)
+		return fs
+	}
+
+	fs := newSet(`{"timeout": "1m30s", "interval": 45, "delay": 1.5}`)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fs.GetDuration("timeout") != 90*time.Second {
+		t.Errorf("Expected timeout 1m30s, got %v", fs.GetDuration("timeout"))
+	}
+	if fs.GetDuration("interval") != 45*time.Second {
+		t.Errorf("Expected interval 45s, got %v", fs.GetDuration("interval"))
+	}
+	if fs.GetDuration("delay") != 90*time.Second {
+		t.Errorf("Expected delay 1.5m, got %v", fs.GetDuration("delay"))
+	}
+
+	err := newSet(`{"timeout": 10}`).Parse(nil)
+	verifyExpectedError(t, err, "config file error: failed to set flag timeout from config: invalid duration value for flag --timeout: 10", "Expected a unit-less number to need a default unit")
+
+	err = newSet(`{"timeout": true}`).Parse(nil)
+	verifyExpectedError(t, err, "config file error: failed to set flag timeout from config: expected duration string or number for flag timeout, got bool", "Expected non-duration config value to be rejected")
+}
+
+// TestUnicodeFlagNames tests flags with non-ASCII names
+func TestUnicodeFlagNames(t *testing.T) {
+	t.Run("parse and help", func(t *testing.T) {
+		fs := New("test")
+		cafe := fs.String("café", "espresso", "Coffee type")
+		fs.String("host", "localhost", "Server host")
+
+		if err := fs.Parse([]string{"--café=latte"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *cafe != "latte" {
+			t.Errorf("Expected 'latte', got '%s'", *cafe)
+		}
+
+		cafeLine, _ := fs.FlagHelp("café")
+		hostLine, _ := fs.FlagHelp("host")
+		cafeColumn := utf8.RuneCountInString(cafeLine[:strings.Index(cafeLine, "Coffee")])
+		hostColumn := utf8.RuneCountInString(hostLine[:strings.Index(hostLine, "Server")])
+		if cafeColumn != hostColumn {
+			t.Errorf("Expected aligned descriptions, got columns %d and %d:\n%s%s", cafeColumn, hostColumn, cafeLine, hostLine)
+		}
+	})
+
+	t.Run("env mapping", func(t *testing.T) {
+		t.Setenv("APP_CAFÉ", "mocha")
+		t.Setenv("APP_CAFE", "ristretto")
+		t.Setenv("APP_HOST", "example.com")
+
+		fs := New("test")
+		cafe := fs.String("café", "espresso", "Coffee type")
+		host := fs.String("host", "localhost", "Server host")
+		fs.SetEnvPrefix("APP")
+
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *cafe != "espresso" {
+			t.Errorf("Expected no automatic env mapping for a non-ASCII name, got '%s'", *cafe)
+		}
+		if *host != "example.com" {
+			t.Errorf("Expected ASCII flag to map to APP_HOST, got '%s'", *host)
+		}
+
+		explicit := New("test")
+		cafe = explicit.String("café", "espresso", "Coffee type")
+		explicit.SetEnvPrefix("APP")
+		if err := explicit.SetEnvVar("café", "APP_CAFE"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := explicit.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *cafe != "ristretto" {
+			t.Errorf("Expected explicit env var to apply, got '%s'", *cafe)
+		}
+	})
+}
+
+// TestRawArgs tests access to the arguments passed to Parse
+func TestRawArgs(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Server host")
+	fs.Bool("verbose", false, "Verbose output")
+
+	if fs.RawArgs() != nil {
+		t.Errorf("Expected nil before Parse, got %v", fs.RawArgs())
+	}
+
+	input := []string{"--host=example.com", "-verbose", "--", "file.txt"}
+	fs.SetSingleDashLongFlags(true)
+	if err := fs.Parse(input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	raw := fs.RawArgs()
+	if strings.Join(raw, " ") != strings.Join(input, " ") {
+		t.Errorf("Expected %v, got %v", input, raw)
+	}
+
+	input[0] = "--host=changed"
+	if fs.RawArgs()[0] != "--host=example.com" {
+		t.Error("Expected RawArgs to be independent of the caller's slice")
+	}
+	_ = append(raw, "extra")
+	if len(fs.RawArgs()) != 4 {
+		t.Error("Expected appends to the returned slice not to affect the FlagSet")
+	}
+}
+
+// TestSetHelpShowBoolDefault tests rendering of boolean defaults in help
+func TestSetHelpShowBoolDefault(t *testing.T) {
+	fs := New("test")
+	fs.Bool("tls", true, "Enable TLS")
+	fs.Bool("debug", false, "Debug mode")
+
+	if line, _ := fs.FlagHelp("tls"); strings.Contains(line, "default") {
+		t.Errorf("Expected bool default hidden by default, got %q", line)
+	}
+
+	fs.SetHelpShowBoolDefault(true)
+	if line, _ := fs.FlagHelp("tls"); !strings.Contains(line, "Enable TLS (default: true)") {
+		t.Errorf("Expected bool default true, got %q", line)
+	}
+	if line, _ := fs.FlagHelp("debug"); !strings.Contains(line, "Debug mode (default: false)") {
+		t.Errorf("Expected bool default false, got %q", line)
+	}
+}
+
+// TestParsed tests reporting whether Parse has been called
+func TestParsed(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Server host")
+
+	if fs.Parsed() {
+		t.Error("Expected Parsed to be false before Parse")
+	}
+	if err := fs.Parse([]string{"--host", "example.com"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !fs.Parsed() {
+		t.Error("Expected Parsed to be true after Parse")
+	}
+
+	failing := New("test")
+	_ = failing.Parse([]string{"--unknown"})
+	if !failing.Parsed() {
+		t.Error("Expected Parsed to be true after a failed Parse")
+	}
+}
+
+// TestSetRequiredOneOfGroups tests exactly-one-complete-group validation
+func TestSetRequiredOneOfGroups(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.String("user", "", "User name")
+		fs.String("pass", "", "Password")
+		fs.String("token", "", "API token")
+		if err := fs.SetRequiredOneOfGroups([][]string{{"user", "pass"}, {"token"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return fs
+	}
+
+	t.Run("zero groups", func(t *testing.T) {
+		err := newSet().Parse(nil)
+		verifyExpectedError(t, err, "one of the flag groups (--user --pass) or (--token) is required", "Expected missing group error")
+	})
+
+	t.Run("partial group", func(t *testing.T) {
+		err := newSet().Parse([]string{"--user", "alice"})
+		verifyExpectedError(t, err, "incomplete flag group (--user --pass): missing --pass", "Expected partial group error")
+	})
+
+	t.Run("one complete group", func(t *testing.T) {
+		if err := newSet().Parse([]string{"--user", "alice", "--pass", "secret"}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := newSet().Parse([]string{"--token", "abc"}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("two groups", func(t *testing.T) {
+		err := newSet().Parse([]string{"--user", "alice", "--pass", "secret", "--token", "abc"})
+		verifyExpectedError(t, err, "only one of the flag groups (--user --pass) or (--token) may be used", "Expected conflicting groups error")
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		fs := New("test")
+		err := fs.SetRequiredOneOfGroups([][]string{{"missing"}})
+		verifyExpectedError(t, err, "flag not found: missing", "Expected unknown flag error")
+	})
+}
+
+// TestSetAssignChar tests a custom name/value separator
+func TestSetAssignChar(t *testing.T) {
+	fs := New("test")
+	port := fs.IntVar("port", "p", 80, "Server port")
+	url := fs.String("url", "", "Endpoint URL")
+	verbose := fs.BoolVar("verbose", "v", false, "Verbose output")
+	if err := fs.SetAssignChar(':'); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := fs.Parse([]string{"--port:8080", "--url:http://host:9000/a=b", "-v:true"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *port != 8080 {
+		t.Errorf("Expected port 8080, got %d", *port)
+	}
+	if *url != "http://host:9000/a=b" {
+		t.Errorf("Expected the value to keep later separators, got '%s'", *url)
+	}
+	if !*verbose {
+		t.Error("Expected verbose to be true")
+	}
+
+	if err := fs.Parse([]string{"-p:9090"}); err != nil || *port != 9090 {
+		t.Errorf("Expected short flag -p:9090 to set 9090, got %d (err: %v)", *port, err)
+	}
+
+	err := fs.Parse([]string{"--port=8080"})
+	verifyExpectedError(t, err, "unknown flag: --port=8080", "Expected '=' to be a plain character")
+
+	t.Run("rejects unusable characters", func(t *testing.T) {
+		cases := map[byte]string{
+			0:    "invalid assign character '\\x00': must be a printable ASCII symbol",
+			' ':  "invalid assign character ' ': must be a printable ASCII symbol",
+			'\t': "invalid assign character '\\t': must be a printable ASCII symbol",
+			0xff: "invalid assign character 'ÿ': must be a printable ASCII symbol",
+			'-':  "invalid assign character '-': conflicts with flag prefixes",
+			'a':  "invalid assign character 'a': may appear in flag names",
+			'Z':  "invalid assign character 'Z': may appear in flag names",
+			'7':  "invalid assign character '7': may appear in flag names",
+		}
+		for ch, expected := range cases {
+			err := fs.SetAssignChar(ch)
+			verifyExpectedError(t, err, expected, "Expected invalid assign character error")
+		}
+		if err := fs.Parse([]string{"--port:7070"}); err != nil || *port != 7070 {
+			t.Errorf("Expected rejected characters to keep ':', got %d (err: %v)", *port, err)
+		}
+	})
+}
+
+// TestFail tests printing a usage error with help and exiting
+func TestFail(t *testing.T) {
+	origExit, origOutput := exitFunc, errorOutput
+	defer func() { exitFunc, errorOutput = origExit, origOutput }()
+
+	code := -1
+	var out strings.Builder
+	exitFunc = func(c int) { code = c }
+	errorOutput = &out
+
+	fs := New("myapp")
+	fs.Int("from", 0, "First row")
+	fs.Fail("--from (%d) must not be after --to (%d)", 20, 10)
+
+	if code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.HasPrefix(out.String(), "myapp: --from (20) must not be after --to (10)\n\n") {
+		t.Errorf("Expected formatted message first, got %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), fs.Help()) {
+		t.Errorf("Expected help after the message, got %q", out.String())
+	}
+}
+
+// TestSetDefaultWhen tests defaults that depend on another flag's value
+func TestSetDefaultWhen(t *testing.T) {
+	newSet := func() (*FlagSet, *int) {
+		fs := New("test")
+		fs.String("mode", "dev", "Run mode")
+		cacheSize := fs.Int("cache-size", 64, "Cache size in MB")
+		isMode := func(mode string) func(interface{}) bool {
+			return func(v interface{}) bool { return v == mode }
+		}
+		if err := fs.SetDefaultWhen("cache-size", "mode", isMode("prod"), 1024); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := fs.SetDefaultWhen("cache-size", "mode", isMode("staging"), 256); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return fs, cacheSize
+	}
+
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{nil, 64},
+		{[]string{"--mode", "prod"}, 1024},
+		{[]string{"--mode", "staging"}, 256},
+		{[]string{"--mode", "prod", "--cache-size", "32"}, 32},
+	}
+	for _, tt := range tests {
+		fs, cacheSize := newSet()
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tt.args, err)
+		}
+		if *cacheSize != tt.expected || fs.GetInt("cache-size") != tt.expected {
+			t.Errorf("Args %v: expected cache size %d, got %d", tt.args, tt.expected, *cacheSize)
+		}
+	}
+
+	t.Run("re-resolved on each parse", func(t *testing.T) {
+		fs, cacheSize := newSet()
+		_ = fs.Parse([]string{"--mode", "prod"})
+		fs.Reset()
+		_ = fs.Parse(nil)
+		if *cacheSize != 64 {
+			t.Errorf("Expected regular default after mode reset, got %d", *cacheSize)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs, _ := newSet()
+		always := func(interface{}) bool { return true }
+		verifyExpectedError(t, fs.SetDefaultWhen("missing", "mode", always, 1), "flag not found: missing", "unknown flag")
+		verifyExpectedError(t, fs.SetDefaultWhen("cache-size", "missing", always, 1), "flag not found: missing", "unknown other flag")
+		verifyExpectedError(t, fs.SetDefaultWhen("cache-size", "mode", always, "big"), "default for flag --cache-size must be int, got string", "wrong type")
+		verifyExpectedError(t, fs.SetDefaultWhen("cache-size", "mode", nil, 1), "predicate for flag --cache-size cannot be nil", "nil predicate")
+		verifyExpectedError(t, fs.SetDefaultWhen("cache-size", "cache-size", always, 1), "conditional default for flag --cache-size on --cache-size creates a cycle", "self reference")
+		verifyExpectedError(t, fs.SetDefaultWhen("mode", "cache-size", always, "prod"), "conditional default for flag --mode on --cache-size creates a cycle", "cycle")
+	})
+
+	t.Run("chained conditions", func(t *testing.T) {
+		// Repeat to cover map iteration order
+		for i := 0; i < 20; i++ {
+			fs := New("test")
+			fs.String("env", "local", "Environment")
+			mode := fs.String("mode", "dev", "Run mode")
+			workers := fs.Int("workers", 1, "Workers")
+			_ = fs.SetDefaultWhen("workers", "mode", func(v interface{}) bool { return v == "prod" }, 16)
+			_ = fs.SetDefaultWhen("mode", "env", func(v interface{}) bool { return v == "cloud" }, "prod")
+
+			if err := fs.Parse([]string{"--env", "cloud"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *mode != "prod" || *workers != 16 {
+				t.Fatalf("Expected mode prod and 16 workers, got %s and %d", *mode, *workers)
+			}
+		}
+	})
+}
+
+// TestAdapterTypedValues tests typed value access through the adapter
+func TestAdapterTypedValues(t *testing.T) {
+	fs := New("test")
+	fs.String("host", "localhost", "Host")
+	fs.Int("port", 8080, "Port")
+	fs.Bool("debug", true, "Debug")
+	fs.Float64("ratio", 0.5, "Ratio")
+	fs.Duration("timeout", time.Second, "Timeout")
+	fs.StringSlice("tags", []string{"a"}, "Tags")
+	fs.StringMap("labels", map[string]string{"env": "prod"}, "Labels")
+	fs.Bytes("max-body", 1024, "Max body")
+	fs.Uint64("max-items", 7, "Max items")
+	adapter := NewAdapter(fs)
+
+	if v, ok := adapter.StringValue("host"); !ok || v != "localhost" {
+		t.Errorf("StringValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.IntValue("port"); !ok || v != 8080 {
+		t.Errorf("IntValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.BoolValue("debug"); !ok || !v {
+		t.Errorf("BoolValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.Float64Value("ratio"); !ok || v != 0.5 {
+		t.Errorf("Float64Value: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.DurationValue("timeout"); !ok || v != time.Second {
+		t.Errorf("DurationValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.StringSliceValue("tags"); !ok || len(v) != 1 || v[0] != "a" {
+		t.Errorf("StringSliceValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.StringMapValue("labels"); !ok || v["env"] != "prod" {
+		t.Errorf("StringMapValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.BytesValue("max-body"); !ok || v != 1024 {
+		t.Errorf("BytesValue: got %v, %t", v, ok)
+	}
+	if v, ok := adapter.Uint64Value("max-items"); !ok || v != 7 {
+		t.Errorf("Uint64Value: got %v, %t", v, ok)
+	}
+
+	if _, ok := adapter.IntValue("host"); ok {
+		t.Error("Expected IntValue to fail for a string flag")
+	}
+	if _, ok := adapter.StringValue("missing"); ok {
+		t.Error("Expected StringValue to fail for a missing flag")
+	}
+}
+
+// TestSetSliceEscaping tests backslash escaping in list values
+func TestSetSliceEscaping(t *testing.T) {
+	tests := []struct {
+		input    string
+		escaping bool
+		expected []string
+	}{
+		{`a\,b,c`, true, []string{"a,b", "c"}},
+		{`a\\,b`, true, []string{`a\`, "b"}},
+		{`path\\to\,x`, true, []string{`path\to,x`}},
+		{`a,b`, true, []string{"a", "b"}},
+		{`a\,b,c`, false, []string{`a\`, "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		fs := New("test")
+		values := fs.StringSlice("match", nil, "Patterns")
+		fs.SetSliceEscaping(tt.escaping)
+		if err := fs.Parse([]string{"--match=" + tt.input}); err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tt.input, err)
+		}
+		if fmt.Sprintf("%q", *values) != fmt.Sprintf("%q", tt.expected) {
+			t.Errorf("Input %q (escaping %t): expected %q, got %q", tt.input, tt.escaping, tt.expected, *values)
+		}
+	}
+}
+
+// TestRequiredWithDefault tests detection of required flags masked by a default
+func TestRequiredWithDefault(t *testing.T) {
+	t.Run("CheckConsistency", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 8080, "Server port")
+		fs.String("api-key", "", "API key")
+		fs.StringSlice("tags", []string{}, "Tags")
+		for _, name := range []string{"port", "api-key", "tags"} {
+			if err := fs.SetRequired(name); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		err := fs.CheckConsistency()
+		verifyExpectedError(t, err, "required flag --port has non-zero default 8080", "Expected masked required flag")
+	})
+
+	t.Run("strict setup rejects at SetRequired", func(t *testing.T) {
+		fs := New("test")
+		fs.SetStrictSetup(true)
+		fs.String("host", "localhost", "Server host")
+		fs.String("token", "", "Token")
+
+		err := fs.SetRequired("host")
+		verifyExpectedError(t, err, "required flag --host has non-zero default localhost", "Expected SetRequired to fail")
+		if fs.Lookup("host").required {
+			t.Error("Expected the flag not to be marked required")
+		}
+		if err := fs.SetRequired("token"); err != nil {
+			t.Errorf("Unexpected error for zero default: %v", err)
+		}
+	})
+}
+
+// TestParseEnvArgs tests parsing flags from a shell-split environment variable
+func TestParseEnvArgs(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := New("test")
+		fs.String("host", "localhost", "Server host")
+		fs.Int("port", 80, "Server port")
+		fs.String("motd", "", "Message of the day")
+		fs.String("path", "", "Path")
+		return fs
+	}
+
+	t.Run("quoted tokens", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", `--host env.example.com --motd "hello  world" --path='C:\dir with space' --port 8080`)
+		fs := newSet()
+		if err := fs.ParseEnvArgs("TEST_ARGS", []string{"--port", "9090"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetString("host") != "env.example.com" {
+			t.Errorf("Expected host from env, got '%s'", fs.GetString("host"))
+		}
+		if fs.GetString("motd") != "hello  world" {
+			t.Errorf("Expected double-quoted value, got '%s'", fs.GetString("motd"))
+		}
+		if fs.GetString("path") != `C:\dir with space` {
+			t.Errorf("Expected single-quoted value, got '%s'", fs.GetString("path"))
+		}
+		if fs.GetInt("port") != 9090 {
+			t.Errorf("Expected explicit port to win, got %d", fs.GetInt("port"))
+		}
+	})
+
+	t.Run("escaped space", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", `--motd hello\ world`)
+		fs := newSet()
+		if err := fs.ParseEnvArgs("TEST_ARGS", nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetString("motd") != "hello world" {
+			t.Errorf("Expected escaped space, got '%s'", fs.GetString("motd"))
+		}
+	})
+
+	t.Run("unterminated quote", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", `--motd "hello`)
+		err := newSet().ParseEnvArgs("TEST_ARGS", nil)
+		verifyExpectedError(t, err, `invalid environment variable TEST_ARGS: unterminated " quote`, "Expected quoting error")
+	})
+
+	t.Run("flag in both is not a repeat", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", "--port 8080")
+		fs := newSet()
+		_ = fs.SetNoRepeat("port")
+		if err := fs.ParseEnvArgs("TEST_ARGS", []string{"--port", "9090"}); err != nil || fs.GetInt("port") != 9090 {
+			t.Errorf("Expected explicit port to win, got %d (err: %v)", fs.GetInt("port"), err)
+		}
+	})
+
+	t.Run("counts and struct slices are replaced", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", "-vvv --route /env")
+		fs := newSet()
+		verbose := fs.CountVar("verbose", "v", "Verbosity")
+		routes := fs.StructSlice("route", func(s string) (interface{}, error) { return s, nil }, "Routes")
+		if err := fs.ParseEnvArgs("TEST_ARGS", []string{"-v", "--route", "/cli"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *verbose != 1 {
+			t.Errorf("Expected CLI count to replace env count, got %d", *verbose)
+		}
+		if len(*routes) != 1 || (*routes)[0] != "/cli" {
+			t.Errorf("Expected CLI routes to replace env routes, got %v", *routes)
+		}
+
+		if err := fs.ParseEnvArgs("TEST_ARGS", nil); err != nil || *verbose != 3 || len(*routes) != 1 {
+			t.Errorf("Expected env values alone, got %d %v (err: %v)", *verbose, *routes, err)
+		}
+	})
+
+	t.Run("separator ends only env flags", func(t *testing.T) {
+		t.Setenv("TEST_ARGS", "--host env.example.com -- env-file")
+		fs := newSet()
+		if err := fs.ParseEnvArgs("TEST_ARGS", []string{"--port", "9090", "cli-file"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetString("host") != "env.example.com" || fs.GetInt("port") != 9090 {
+			t.Errorf("Expected flags from both sources, got host %s port %d", fs.GetString("host"), fs.GetInt("port"))
+		}
+		if args := fs.Args(); len(args) != 2 || args[0] != "env-file" || args[1] != "cli-file" {
+			t.Errorf("Expected env positionals before CLI positionals, got %v", args)
+		}
+	})
+
+	t.Run("unset variable", func(t *testing.T) {
+		fs := newSet()
+		if err := fs.ParseEnvArgs("TEST_ARGS_UNSET", []string{"--port", "81"}); err != nil || fs.GetInt("port") != 81 {
+			t.Errorf("Expected extra args only, got port %d (err: %v)", fs.GetInt("port"), err)
+		}
+	})
+}
+
+// TestSetFlagExample tests per-flag help examples
+func TestSetFlagExample(t *testing.T) {
+	fs := New("test")
+	fs.StringMap("filter", nil, "Filter results")
+	if err := fs.SetFlagExample("filter", "--filter status=active,team=infra"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	line, _ := fs.FlagHelp("filter")
+	expected := "\n" + strings.Repeat(" ", 30) + "e.g. --filter status=active,team=infra\n"
+	if !strings.HasSuffix(line, expected) {
+		t.Errorf("Expected example under the flag line, got %q", line)
+	}
+	if !strings.Contains(fs.Help(), line) {
+		t.Errorf("Expected Help() to include the example, got:\n%s", fs.Help())
+	}
+	if entries := fs.HelpData(); entries[0].Example != "--filter status=active,team=infra" {
+		t.Errorf("Expected example in HelpData, got %q", entries[0].Example)
+	}
+
+	verifyExpectedError(t, fs.SetFlagExample("missing", "x"), "flag not found: missing", "Expected unknown flag error")
+}
+
+// TestCheckValue tests validating candidate values without mutating flags
+func TestCheckValue(t *testing.T) {
+	t.Run("valid and invalid across types", func(t *testing.T) {
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.String("mode", "dev", "Mode")
+		fs.Bool("debug", false, "Debug")
+		_ = fs.SetIntRange("port", 1, 65535)
+		_ = fs.SetAllowedValues("mode", "dev", "prod")
+
+		valid := map[string]string{"port": "9090", "timeout": "5s", "mode": "prod", "debug": "true"}
+		for name, raw := range valid {
+			if err := fs.CheckValue(name, raw); err != nil {
+				t.Errorf("CheckValue(%s, %q) unexpected error: %v", name, raw, err)
+			}
+		}
+
+		invalid := map[string]string{"port": "70000", "timeout": "soon", "mode": "staging", "debug": "maybe"}
+		for name, raw := range invalid {
+			if err := fs.CheckValue(name, raw); err == nil {
+				t.Errorf("CheckValue(%s, %q) expected error", name, raw)
+			}
+		}
+
+		if *port != 8080 || fs.Changed("port") {
+			t.Errorf("Expected port untouched, got %d (changed=%v)", *port, fs.Changed("port"))
+		}
+		if fs.GetDuration("timeout") != time.Second || fs.GetString("mode") != "dev" {
+			t.Error("Expected values untouched after CheckValue")
+		}
+	})
+
+	t.Run("validator", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("workers", 4, "Workers")
+		_ = fs.SetValidator("workers", func(v interface{}) error {
+			if v.(int)%2 != 0 {
+				return fmt.Errorf("must be even")
+			}
+			return nil
+		})
+
+		if err := fs.CheckValue("workers", "6"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		err := fs.CheckValue("workers", "3")
+		verifyExpectedError(t, err, "validation failed for flag --workers: must be even", "odd workers")
+		if len(fs.validationErrs) != 0 {
+			t.Errorf("Expected no recorded validation errors, got %v", fs.validationErrs)
+		}
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		fs := New("test")
+		err := fs.CheckValue("missing", "x")
+		verifyExpectedError(t, err, "flag not found: missing", "unknown flag")
+	})
+}
+
+// TestAddConfigGlob tests loading conf.d-style config files in sorted order
+func TestAddConfigGlob(t *testing.T) {
+	t.Run("merges matching files in order", func(t *testing.T) {
+		dir := t.TempDir()
+		confDir := filepath.Join(dir, "conf.d")
+		if err := os.Mkdir(confDir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{
+			"20-override.json": `{"port": 9090, "debug": true}`,
+			"10-base.json":     `{"host": "db.local", "port": 8081}`,
+			"30-last.json":     `{"port": 9999}`,
+			"notes.txt":        `{"host": "ignored"}`,
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(confDir, name), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mainConfig := filepath.Join(dir, "app.json")
+		if err := os.WriteFile(mainConfig, []byte(`{"host": "main", "timeout": "3s"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		fs := New("app")
+		fs.String("host", "localhost", "Host")
+		fs.Int("port", 8080, "Port")
+		fs.Bool("debug", false, "Debug")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.SetConfigFile(mainConfig)
+		fs.AddConfigGlob(filepath.Join(confDir, "*.json"))
+
+		if err := fs.Parse([]string{"--debug=false"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetString("host") != "db.local" {
+			t.Errorf("Expected host from 10-base.json, got %s", fs.GetString("host"))
+		}
+		if fs.GetInt("port") != 9999 {
+			t.Errorf("Expected port from 30-last.json, got %d", fs.GetInt("port"))
+		}
+		if fs.GetDuration("timeout") != 3*time.Second {
+			t.Errorf("Expected timeout from main config, got %v", fs.GetDuration("timeout"))
+		}
+		if fs.GetBool("debug") {
+			t.Error("Expected command line to win over config")
+		}
+		if fs.Lookup("port").Source() != "config" {
+			t.Errorf("Expected config source, got %q", fs.Lookup("port").Source())
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		fs := New("app")
+		fs.Int("port", 8080, "Port")
+		fs.AddConfigGlob(filepath.Join(t.TempDir(), "*.json"))
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fs.GetInt("port") != 8080 {
+			t.Errorf("Expected default port, got %d", fs.GetInt("port"))
+		}
+	})
+
+	t.Run("rejects traversal and bad patterns", func(t *testing.T) {
+		fs := New("app")
+		fs.AddConfigGlob("../conf.d/*.json")
+		err := fs.Parse(nil)
+		verifyExpectedError(t, err, "config file error: invalid config file path: ../conf.d/*.json", "traversal")
+
+		fs = New("app")
+		fs.AddConfigGlob("conf.d/[")
+		if err := fs.Parse(nil); err == nil || !strings.Contains(err.Error(), "invalid config glob conf.d/[") {
+			t.Errorf("Expected invalid glob error, got %v", err)
+		}
+	})
+}
+
+// TestEnableShowConfig tests printing the resolved configuration with --show-config
+func TestEnableShowConfig(t *testing.T) {
+	newFlagSet := func() *FlagSet {
+		fs := New("app")
+		fs.String("host", "localhost", "Host")
+		fs.Int("port", 8080, "Port")
+		fs.Duration("timeout", time.Second, "Timeout")
+		fs.String("password", "", "Password")
+		_ = fs.SetSecret("password")
+		_ = fs.SetRequired("password")
+		fs.EnableShowConfig("json")
+		return fs
+	}
+
+	t.Run("json with resolved values", func(t *testing.T) {
+		fs := newFlagSet()
+		var err error
+		output := captureStdout(t, func() {
+			err = fs.Parse([]string{"--show-config", "json", "--port", "9090", "--password=hunter2"})
+		})
+		if !errors.Is(err, ErrShowConfig) {
+			t.Fatalf("Expected ErrShowConfig, got %v", err)
+		}
+
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &values); err != nil {
+			t.Fatalf("Expected JSON output, got %q: %v", output, err)
+		}
+		if values["port"] != float64(9090) || values["host"] != "localhost" || values["timeout"] != "1s" {
+			t.Errorf("Unexpected resolved values: %v", values)
+		}
+		if _, ok := values["password"]; ok {
+			t.Error("Expected secret flag to be left out")
+		}
+	})
+
+	t.Run("default format and env format", func(t *testing.T) {
+		fs := newFlagSet()
+		var err error
+		output := captureStdout(t, func() {
+			err = fs.Parse([]string{"--show-config"})
+		})
+		if !errors.Is(err, ErrShowConfig) {
+			t.Fatalf("Expected ErrShowConfig without required flags, got %v", err)
+		}
+		if !strings.Contains(output, `"port": 8080`) {
+			t.Errorf("Expected JSON output, got %q", output)
+		}
+
+		fs = newFlagSet()
+		fs.SetEnvPrefix("APP")
+		output = captureStdout(t, func() {
+			err = fs.Parse([]string{"--show-config=env"})
+		})
+		if !errors.Is(err, ErrShowConfig) {
+			t.Fatalf("Expected ErrShowConfig, got %v", err)
+		}
+		if !strings.Contains(output, "export APP_PORT=8080\n") || strings.Contains(output, "PASSWORD") {
+			t.Errorf("Unexpected env output %q", output)
+		}
+	})
+
+	t.Run("custom assign char", func(t *testing.T) {
+		fs := newFlagSet()
+		fs.SetEnvPrefix("APP")
+		if err := fs.SetAssignChar(':'); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var err error
+		output := captureStdout(t, func() {
+			err = fs.Parse([]string{"--show-config:env", "--port:9090"})
+		})
+		if !errors.Is(err, ErrShowConfig) {
+			t.Fatalf("Expected ErrShowConfig, got %v", err)
+		}
+		if !strings.Contains(output, "export APP_PORT=9090\n") {
+			t.Errorf("Unexpected env output %q", output)
+		}
+	})
+
+	t.Run("disabled and unsupported format", func(t *testing.T) {
+		fs := New("app")
+		if err := fs.Parse([]string{"--show-config"}); err == nil {
+			t.Error("Expected unknown flag error when not enabled")
+		}
+
+		fs = newFlagSet()
+		err := fs.DumpConfig(io.Discard, "yaml")
+		verifyExpectedError(t, err, "unsupported config format: yaml", "yaml format")
+	})
+}
+
+// TestSetEnvFile tests reading environment values from a dotenv file
+func TestSetEnvFile(t *testing.T) {
+	t.Run("inline comments and quoted values", func(t *testing.T) {
+		path := createTempConfigFile(t, `# local settings
+export ENVFILE_HOST=db.local
+ENVFILE_PORT=8080 # the http port
+ENVFILE_MOTD="welcome to #general" # greeting
+ENVFILE_TAG='a # b'
+ENVFILE_PATH=/srv/app#1
+`, "envfile-*.env")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		host := fs.String("host", "localhost", "Host")
+		port := fs.Int("port", 80, "Port")
+		motd := fs.String("motd", "", "Message of the day")
+		tag := fs.String("tag", "", "Tag")
+		dir := fs.String("path", "", "Path")
+		fs.SetEnvPrefix("ENVFILE")
+		fs.SetEnvFile(path)
+
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *host != "db.local" || *port != 8080 {
+			t.Errorf("Expected db.local:8080, got %s:%d", *host, *port)
+		}
+		if *motd != "welcome to #general" || *tag != "a # b" {
+			t.Errorf("Expected # kept inside quotes, got %q and %q", *motd, *tag)
+		}
+		if *dir != "/srv/app#1" {
+			t.Errorf("Expected # without leading space kept, got %q", *dir)
+		}
+	})
+
+	t.Run("environment wins over file", func(t *testing.T) {
+		path := createTempConfigFile(t, "ENVFILE2_PORT=8080\n", "envfile-*.env")
+		defer func() { _ = os.Remove(path) }()
+		t.Setenv("ENVFILE2_PORT", "9090")
+
+		fs := New("test")
+		port := fs.Int("port", 80, "Port")
+		fs.SetEnvPrefix("ENVFILE2")
+		fs.SetEnvFile(path)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *port != 9090 {
+			t.Errorf("Expected environment value 9090, got %d", *port)
+		}
+	})
+
+	t.Run("missing file and malformed lines", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 80, "Port")
+		fs.SetEnvPrefix("ENVFILE3")
+		fs.SetEnvFile(filepath.Join(t.TempDir(), "missing.env"))
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Expected missing env file to be ignored, got %v", err)
+		}
+
+		path := createTempConfigFile(t, "ENVFILE3_PORT=\"8080\n", "envfile-*.env")
+		defer func() { _ = os.Remove(path) }()
+		fs = New("test")
+		fs.Int("port", 80, "Port")
+		fs.SetEnvPrefix("ENVFILE3")
+		fs.SetEnvFile(path)
+		err := fs.Parse(nil)
+		verifyExpectedError(t, err, "environment variable error: invalid env file "+path+" line 1: unterminated \" quote", "unterminated quote")
+	})
+}
+
+// TestSetElementValidator tests validating each element of a string slice flag
+func TestSetElementValidator(t *testing.T) {
+	hostname := func(host string) error {
+		if host == "" || strings.ContainsAny(host, " /_") {
+			return fmt.Errorf("invalid hostname")
+		}
+		return nil
+	}
+
+	t.Run("reports the failing element", func(t *testing.T) {
+		fs := New("test")
+		fs.StringSlice("hosts", nil, "Hosts")
+		if err := fs.SetElementValidator("hosts", hostname); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		err := fs.Parse([]string{"--hosts", "a.local,b_bad,c.local"})
+		verifyExpectedError(t, err, `validation failed for flag --hosts: item 1 "b_bad": invalid hostname`, "bad element")
+
+		fs = New("test")
+		hosts := fs.StringSlice("hosts", nil, "Hosts")
+		_ = fs.SetElementValidator("hosts", hostname)
+		if err := fs.Parse([]string{"--hosts", "a.local,b.local,c.local"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*hosts) != 3 {
+			t.Errorf("Expected 3 hosts, got %v", *hosts)
+		}
+	})
+
+	t.Run("keeps existing validator", func(t *testing.T) {
+		fs := New("test")
+		fs.StringSlice("hosts", nil, "Hosts")
+		_ = fs.SetValidator("hosts", func(v interface{}) error {
+			if len(v.([]string)) > 2 {
+				return fmt.Errorf("too many hosts")
+			}
+			return nil
+		})
+		_ = fs.SetElementValidator("hosts", hostname)
+
+		err := fs.Parse([]string{"--hosts", "a,b,c"})
+		verifyExpectedError(t, err, "validation failed for flag --hosts: too many hosts", "slice validator")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "", "Host")
+		err := fs.SetElementValidator("host", hostname)
+		verifyExpectedError(t, err, "flag --host is not a string slice flag", "wrong type")
+		err = fs.SetElementValidator("missing", hostname)
+		verifyExpectedError(t, err, "flag not found: missing", "missing flag")
+	})
+}
+
+// TestIntSlice tests int slice flags from the command line, environment, and config
+func TestIntSlice(t *testing.T) {
+	t.Run("env CSV and JSON produce the same slice", func(t *testing.T) {
+		for _, envValue := range []string{"80,443", "80, 443", "[80,443]", " [80, 443] "} {
+			t.Setenv("INTSLICE_PORTS", envValue)
+			fs := New("test")
+			ports := fs.IntSlice("ports", []int{8080}, "Ports")
+			fs.SetEnvPrefix("INTSLICE")
+			if err := fs.Parse(nil); err != nil {
+				t.Fatalf("Unexpected error for %q: %v", envValue, err)
+			}
+			if len(*ports) != 2 || (*ports)[0] != 80 || (*ports)[1] != 443 {
+				t.Errorf("Expected [80 443] from %q, got %v", envValue, *ports)
+			}
+		}
+	})
+
+	t.Run("invalid elements", func(t *testing.T) {
+		t.Setenv("INTSLICE_PORTS", "80,http,443")
+		fs := New("test")
+		fs.IntSlice("ports", nil, "Ports")
+		fs.SetEnvPrefix("INTSLICE")
+		err := fs.Parse(nil)
+		verifyExpectedError(t, err, "environment variable error: invalid environment variable INTSLICE_PORTS=80,http,443: invalid int value for flag --ports item 1: http", "CSV element")
+
+		t.Setenv("INTSLICE_PORTS", `[80,"443"]`)
+		fs = New("test")
+		fs.IntSlice("ports", nil, "Ports")
+		fs.SetEnvPrefix("INTSLICE")
+		if err := fs.Parse(nil); err == nil || !strings.Contains(err.Error(), "invalid int slice value for flag --ports") {
+			t.Errorf("Expected JSON element error, got %v", err)
+		}
+	})
+
+	t.Run("command line, config, and reset", func(t *testing.T) {
+		configFile := createTempConfigFile(t, `{"ports": [1, 2, 3]}`, "intslice-*.json")
+		defer func() { _ = os.Remove(configFile) }()
+
+		fs := New("test")
+		ports := fs.IntSlice("ports", []int{8080}, "Ports")
+		fs.SetConfigFile(configFile)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := fs.GetIntSlice("ports"); len(got) != 3 || got[2] != 3 {
+			t.Errorf("Expected config ports, got %v", got)
+		}
+
+		if err := fs.Set("ports", "9000,9001"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*ports) != 2 || (*ports)[1] != 9001 {
+			t.Errorf("Expected [9000 9001], got %v", *ports)
+		}
+
+		fs.Reset()
+		if len(*ports) != 1 || (*ports)[0] != 8080 {
+			t.Errorf("Expected default after reset, got %v", *ports)
+		}
+		if help := fs.Help(); !strings.Contains(help, "--ports LIST") {
+			t.Errorf("Expected LIST label in help, got %q", help)
+		}
+	})
+}
+
+// TestSetHideDefault tests omitting default values from help lines
+func TestSetHideDefault(t *testing.T) {
+	t.Run("per flag", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 8080, "Server port")
+		if err := fs.SetHideDefault("port"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		help := fs.Help()
+		if !strings.Contains(help, "Server port\n") || strings.Contains(help, "(default") {
+			t.Errorf("Expected default omitted, got %q", help)
+		}
+
+		err := fs.SetHideDefault("missing")
+		verifyExpectedError(t, err, "flag not found: missing", "missing flag")
+	})
+
+	t.Run("zero defaults", func(t *testing.T) {
+		cases := []struct {
+			register func(fs *FlagSet)
+			usage    string
+			hidden   bool
+		}{
+			{func(fs *FlagSet) { fs.String("x", "", "Name") }, "Name", true},
+			{func(fs *FlagSet) { fs.Int("x", 0, "Count") }, "Count", true},
+			{func(fs *FlagSet) { fs.Duration("x", 0, "Delay") }, "Delay", true},
+			{func(fs *FlagSet) { fs.StringSlice("x", nil, "Tags") }, "Tags", true},
+			{func(fs *FlagSet) { fs.Int("x", 8080, "Port") }, "Port", false},
+			{func(fs *FlagSet) { fs.String("x", "info", "Level") }, "Level", false},
+		}
+		for _, tc := range cases {
+			fs := New("test")
+			tc.register(fs)
+			if !strings.Contains(fs.Help(), tc.usage+" (default: ") {
+				t.Errorf("Expected %s default shown without the option", tc.usage)
+			}
+
+			fs.SetHideZeroDefaults(true)
+			shown := strings.Contains(fs.Help(), tc.usage+" (default: ")
+			if shown == tc.hidden {
+				t.Errorf("%s: expected hidden=%v, got help %q", tc.usage, tc.hidden, fs.Help())
+			}
+		}
+	})
+
+	t.Run("default display text is kept", func(t *testing.T) {
+		fs := New("test")
+		fs.String("dir", "", "Data directory")
+		_ = fs.SetDefaultDisplay("dir", "$XDG_DATA_HOME")
+		fs.SetHideZeroDefaults(true)
+		if !strings.Contains(fs.Help(), "Data directory (default: $XDG_DATA_HOME)") {
+			t.Errorf("Expected display text kept, got %q", fs.Help())
+		}
+	})
+}
+
+// TestValidateStructure tests checking structural constraints without validators
+func TestValidateStructure(t *testing.T) {
+	newFlagSet := func(calls *int) *FlagSet {
+		fs := New("test")
+		fs.String("host", "", "Host")
+		fs.Bool("tls", false, "Enable TLS")
+		fs.String("cert", "", "Certificate")
+		_ = fs.SetRequired("host")
+		_ = fs.SetDependencies("cert", "tls")
+		_ = fs.SetValidator("host", func(interface{}) error {
+			*calls++
+			return fmt.Errorf("host unreachable")
+		})
+		return fs
+	}
+
+	t.Run("passes when only a validator would fail", func(t *testing.T) {
+		calls := 0
+		fs := newFlagSet(&calls)
+		_ = fs.Set("host", "db.local")
+		calls = 0
+
+		if err := fs.ValidateStructure(); err != nil {
+			t.Errorf("Expected structure to pass, got %v", err)
+		}
+		if calls != 0 {
+			t.Errorf("Expected validator not to run, ran %d times", calls)
+		}
+		err := fs.ValidateAllConstraints()
+		verifyExpectedError(t, err, "validation failed for flag --host: host unreachable", "full validation")
+	})
+
+	t.Run("reports structural errors", func(t *testing.T) {
+		calls := 0
+		fs := newFlagSet(&calls)
+		err := fs.ValidateStructure()
+		verifyExpectedError(t, err, "required flag --host not provided", "missing required")
+
+		_ = fs.Set("host", "db.local")
+		_ = fs.Set("cert", "server.pem")
+		if err := fs.ValidateStructure(); err == nil || !strings.Contains(err.Error(), "--cert") {
+			t.Errorf("Expected dependency error, got %v", err)
+		}
+	})
+}
+
+// TestResetAll tests reusing a flag set across independent parses
+func TestResetAll(t *testing.T) {
+	configFile := createTempConfigFile(t, `{"host": "config.local"}`, "resetall-*.json")
+	defer func() { _ = os.Remove(configFile) }()
+
+	fs := New("test")
+	host := fs.String("host", "localhost", "Host")
+	port := fs.Int("port", 8080, "Port")
+	fs.SetConfigFile(configFile)
+	fs.SetEnvPrefix("RESETALL")
+
+	if err := fs.Parse([]string{"--port", "9090", "serve"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *host != "config.local" || *port != 9090 || len(fs.Args()) != 1 {
+		t.Fatalf("Unexpected first parse: host=%s port=%d args=%v", *host, *port, fs.Args())
+	}
+
+	fs.ResetAll()
+	if fs.Parsed() || len(fs.Args()) != 0 || len(fs.RawArgs()) != 0 {
+		t.Errorf("Expected cleared parse state, parsed=%v args=%v raw=%v", fs.Parsed(), fs.Args(), fs.RawArgs())
+	}
+	if *host != "localhost" || *port != 8080 || fs.Changed("port") {
+		t.Errorf("Expected defaults after ResetAll, got host=%s port=%d", *host, *port)
+	}
+
+	if err := os.WriteFile(configFile, []byte(`{"host": "other.local"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RESETALL_PORT", "7070")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *host != "other.local" {
+		t.Errorf("Expected config re-read, got host=%s", *host)
+	}
+	if *port != 7070 || fs.Lookup("port").Source() != "env" {
+		t.Errorf("Expected port from env, got %d (%s)", *port, fs.Lookup("port").Source())
+	}
+	if !fs.Parsed() || len(fs.Args()) != 0 {
+		t.Errorf("Expected parsed with no args, got parsed=%v args=%v", fs.Parsed(), fs.Args())
+	}
+}
+
+// TestSetExtraFlagsSink tests collecting unknown --key=value flags
+func TestSetExtraFlagsSink(t *testing.T) {
+	t.Run("collects unknown pairs", func(t *testing.T) {
+		fs := New("test")
+		port := fs.Int("port", 8080, "Port")
+		debug := fs.BoolVar("debug", "d", false, "Debug")
+		var extra map[string]string
+		fs.SetExtraFlagsSink(&extra)
+
+		err := fs.Parse([]string{"--jvm-heap=2g", "--port", "9090", "--gc=g1", "-d", "run", "--opts=a=b", "--gc=zgc"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if *port != 9090 || !*debug {
+			t.Errorf("Expected known flags parsed, got port=%d debug=%v", *port, *debug)
+		}
+		expected := map[string]string{"jvm-heap": "2g", "gc": "zgc", "opts": "a=b"}
+		if len(extra) != len(expected) {
+			t.Errorf("Expected %v, got %v", expected, extra)
+		}
+		for k, v := range expected {
+			if extra[k] != v {
+				t.Errorf("Expected extra[%s]=%q, got %q", k, v, extra[k])
+			}
+		}
+		if args := fs.Args(); len(args) != 1 || args[0] != "run" {
+			t.Errorf("Expected positional args [run], got %v", args)
+		}
+	})
+
+	t.Run("unknown flags without value still fail", func(t *testing.T) {
+		fs := New("test")
+		extra := map[string]string{"keep": "me"}
+		fs.SetExtraFlagsSink(&extra)
+
+		if err := fs.Parse([]string{"--verbose"}); err == nil {
+			t.Error("Expected unknown flag error for --verbose")
+		}
+		if extra["keep"] != "me" || len(extra) != 1 {
+			t.Errorf("Expected existing entries kept, got %v", extra)
+		}
+	})
+}
+
+// TestSetProfile tests recording per-flag setter and validator cost
+func TestSetProfile(t *testing.T) {
+	t.Run("reports slow validator", func(t *testing.T) {
+		fs := New("test")
+		fs.String("host", "localhost", "Host")
+		fs.Int("port", 8080, "Port")
+		_ = fs.SetValidator("port", func(interface{}) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		fs.SetProfile(true)
+
+		if err := fs.Parse([]string{"--host", "db.local", "--port", "9090"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		report := fs.ProfileReport()
+		if len(report) != 2 {
+			t.Fatalf("Expected entries for host and port, got %+v", report)
+		}
+		slowest := report[0]
+		if slowest.Name != "port" {
+			t.Fatalf("Expected port to be the most expensive flag, got %+v", report)
+		}
+		if slowest.ValidatorCalls == 0 || slowest.ValidatorTime < 5*time.Millisecond {
+			t.Errorf("Expected validator time of at least 5ms, got %+v", slowest)
+		}
+		if slowest.SetterCalls != 1 || report[1].SetterCalls != 1 || report[1].ValidatorCalls != 0 {
+			t.Errorf("Unexpected call counts: %+v", report)
+		}
+	})
+
+	t.Run("disabled and reset", func(t *testing.T) {
+		fs := New("test")
+		fs.Int("port", 8080, "Port")
+		if err := fs.Parse([]string{"--port", "9090"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if report := fs.ProfileReport(); len(report) != 0 {
+			t.Errorf("Expected empty report without profiling, got %+v", report)
+		}
+
+		fs.SetProfile(true)
+		_ = fs.Set("port", "9091")
+		if len(fs.ProfileReport()) != 1 {
+			t.Errorf("Expected one entry, got %+v", fs.ProfileReport())
+		}
+		fs.SetProfile(true)
+		if len(fs.ProfileReport()) != 0 {
+			t.Error("Expected SetProfile to clear measurements")
+		}
+	})
+
+	t.Run("counts values set from each source once", func(t *testing.T) {
+		path := createTempConfigFile(t, `{"port": " 9090 "}`, "profile-*.json")
+		defer func() { _ = os.Remove(path) }()
+
+		fs := New("test")
+		fs.Int("port", 8080, "Port")
+		fs.SetTrimValues(true)
+		fs.SetConfigFile(path)
+		fs.SetProfile(true)
+
+		if err := fs.CheckValue("port", "7070"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if report := fs.ProfileReport(); len(report) != 0 {
+			t.Errorf("Expected CheckValue not to be profiled, got %+v", report)
+		}
+		if err := fs.Parse(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if report := fs.ProfileReport(); len(report) != 1 || report[0].SetterCalls != 1 {
+			t.Errorf("Expected one setter call for the config value, got %+v", report)
+		}
+	})
+}
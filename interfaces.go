@@ -6,6 +6,8 @@
 
 package flashflags
 
+import "time"
+
 // ConfigFlag represents a flag interface for configuration management integration.
 // This interface allows flash-flags to integrate seamlessly with configuration
 // management systems like Argus, Viper, or custom solutions.
@@ -18,6 +20,19 @@ type ConfigFlag interface {
 	Usage() string
 }
 
+// ConfigFlagV2 extends ConfigFlag with the source of the current value, so configuration
+// managers can reason about precedence. Source returns "cli", "env", "config", or empty
+// string for default values. ConfigFlag is kept for compatibility; integrations can
+// detect the extension with a type assertion:
+//
+//	if v2, ok := flag.(flashflags.ConfigFlagV2); ok {
+//		fmt.Println(v2.Source())
+//	}
+type ConfigFlagV2 interface {
+	ConfigFlag
+	Source() string
+}
+
 // ConfigFlagSet represents a collection of flags for configuration integration.
 // This interface provides the standard contract expected by configuration managers.
 // It allows configuration systems to iterate over and access flags in a standardized way.
@@ -57,8 +72,85 @@ func (fsa *FlagSetAdapter) Lookup(name string) ConfigFlag {
 	return nil
 }
 
+// lookupValue returns the value of the named flag, or nil if it doesn't exist
+func (fsa *FlagSetAdapter) lookupValue(name string) interface{} {
+	if flag := fsa.FlagSet.Lookup(name); flag != nil {
+		return flag.Value()
+	}
+	return nil
+}
+
+// StringValue returns the value of a string flag.
+// The boolean is false if the flag doesn't exist or has another type.
+// The other typed accessors follow the same rule, so integrations don't
+// need their own type assertions:
+//
+//	adapter := flashflags.NewAdapter(fs)
+//	if port, ok := adapter.IntValue("port"); ok {
+//		cfg.Port = port
+//	}
+func (fsa *FlagSetAdapter) StringValue(name string) (string, bool) {
+	v, ok := fsa.lookupValue(name).(string)
+	return v, ok
+}
+
+// IntValue returns the value of an int or count flag.
+func (fsa *FlagSetAdapter) IntValue(name string) (int, bool) {
+	v, ok := fsa.lookupValue(name).(int)
+	return v, ok
+}
+
+// BoolValue returns the value of a bool flag.
+func (fsa *FlagSetAdapter) BoolValue(name string) (bool, bool) {
+	v, ok := fsa.lookupValue(name).(bool)
+	return v, ok
+}
+
+// Float64Value returns the value of a float64 flag.
+func (fsa *FlagSetAdapter) Float64Value(name string) (float64, bool) {
+	v, ok := fsa.lookupValue(name).(float64)
+	return v, ok
+}
+
+// DurationValue returns the value of a duration flag.
+func (fsa *FlagSetAdapter) DurationValue(name string) (time.Duration, bool) {
+	v, ok := fsa.lookupValue(name).(time.Duration)
+	return v, ok
+}
+
+// StringSliceValue returns the value of a string slice flag.
+func (fsa *FlagSetAdapter) StringSliceValue(name string) ([]string, bool) {
+	v, ok := fsa.lookupValue(name).([]string)
+	return v, ok
+}
+
+// IntSliceValue returns the value of an int slice flag.
+func (fsa *FlagSetAdapter) IntSliceValue(name string) ([]int, bool) {
+	v, ok := fsa.lookupValue(name).([]int)
+	return v, ok
+}
+
+// StringMapValue returns the value of a string map flag.
+func (fsa *FlagSetAdapter) StringMapValue(name string) (map[string]string, bool) {
+	v, ok := fsa.lookupValue(name).(map[string]string)
+	return v, ok
+}
+
+// BytesValue returns the value of a bytes flag, in bytes.
+func (fsa *FlagSetAdapter) BytesValue(name string) (int64, bool) {
+	v, ok := fsa.lookupValue(name).(int64)
+	return v, ok
+}
+
+// Uint64Value returns the value of a uint64 flag.
+func (fsa *FlagSetAdapter) Uint64Value(name string) (uint64, bool) {
+	v, ok := fsa.lookupValue(name).(uint64)
+	return v, ok
+}
+
 // Ensure our types implement the interfaces
 var (
 	_ ConfigFlag    = (*Flag)(nil)
+	_ ConfigFlagV2  = (*Flag)(nil)
 	_ ConfigFlagSet = (*FlagSetAdapter)(nil)
 )
@@ -529,6 +529,25 @@ func BenchmarkParse_ZeroAlloc(b *testing.B) {
 	}
 }
 
+// BenchmarkArgAccess benchmarks remaining-argument access, which must not allocate
+func BenchmarkArgAccess(b *testing.B) {
+	fs := New("benchmark")
+	fs.String("host", "localhost", "Host")
+	if err := fs.Parse([]string{"--host", "example.com", "file1", "file2", "file3"}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < fs.NArg(); j++ {
+			_ = fs.Arg(j)
+		}
+		_ = fs.Args()
+	}
+}
+
 // =============================================================================
 // COMPARATIVE SECURITY BENCHMARKS
 // =============================================================================
@@ -577,3 +596,24 @@ func BenchmarkSecurityOverhead_Minimal(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkParse_CombinedBooleans benchmarks all-boolean combined short flags (tar-style -xvzf)
+func BenchmarkParse_CombinedBooleans(b *testing.B) {
+	fs := New("benchmark")
+	fs.BoolVar("extract", "x", false, "Extract")
+	fs.BoolVar("verbose", "v", false, "Verbose")
+	fs.BoolVar("gzip", "z", false, "Gzip")
+	fs.BoolVar("force", "f", false, "Force")
+	fs.BoolVar("preserve", "p", false, "Preserve permissions")
+	fs.BoolVar("keep", "k", false, "Keep old files")
+	args := []string{"-xvzf", "-pk", "archive.tar.gz"}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := fs.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
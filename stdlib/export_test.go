@@ -0,0 +1,14 @@
+// export_test.go: test hooks for the stdlib shim
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGILira library
+// SPDX-License-Identifier: MPL-2.0
+
+package stdlib
+
+// SetExitFunc replaces the process exit hook and returns a function restoring it
+func SetExitFunc(fn func(int)) func() {
+	old := exitFunc
+	exitFunc = fn
+	return func() { exitFunc = old }
+}
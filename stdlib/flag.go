@@ -22,8 +22,10 @@ package stdlib
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -34,10 +36,27 @@ import (
 // but no such flag is defined.
 var ErrHelp = errors.New("flag: help requested")
 
+// ErrorHandling defines how Parse behaves if the parse fails, as in the standard library.
+type ErrorHandling int
+
+// These constants cause Parse to behave as described if the parse fails.
+const (
+	ContinueOnError ErrorHandling = iota // Ignore the error and continue.
+	ExitOnError                          // Call os.Exit(2), or os.Exit(0) for -h/-help.
+	PanicOnError                         // Call panic with a descriptive error.
+)
+
+// errorHandling is the CommandLine behavior on parse errors; ExitOnError like the standard library
+var errorHandling = ExitOnError
+
+// exitFunc is the process exit hook; tests replace it
+var exitFunc = os.Exit
+
 // CommandLine is the default set of command-line flags, parsed from os.Args.
 // The top-level functions such as BoolVar, Arg, and so on are wrappers for the
 // methods of CommandLine.
-var CommandLine = flashflags.New(getProgName())
+// Like the standard library, it accepts single-dash long flags (-verbose, -port 8080).
+var CommandLine = newCommandLine()
 
 // Usage is a function to call when a flag is provided but the flag is not defined.
 var Usage = func() {
@@ -58,6 +77,13 @@ var (
 	parsed       bool
 )
 
+// newCommandLine creates the default FlagSet with stdlib-compatible parsing
+func newCommandLine() *flashflags.FlagSet {
+	fs := flashflags.New(getProgName())
+	fs.SetSingleDashLongFlags(true)
+	return fs
+}
+
 // getProgName extracts program name from os.Args[0]
 func getProgName() string {
 	if len(os.Args) == 0 {
@@ -73,15 +99,53 @@ func getProgName() string {
 	return name
 }
 
+// SetProgramName overrides the program name used by CommandLine in help and usage output.
+// By default the name is derived from os.Args[0], which is often unhelpful in tests or
+// when the program is embedded.
+func SetProgramName(name string) {
+	CommandLine.SetName(name)
+}
+
+// SetErrorHandling sets how Parse handles errors. The default is ExitOnError, matching
+// the standard library's CommandLine: the error and usage are printed and the process
+// exits with status 2, or with status 0 after help (and version) output.
+func SetErrorHandling(h ErrorHandling) {
+	errorHandling = h
+}
+
 // Parse parses the command-line flags from os.Args[1:]. Must be called
 // after all flags are defined and before flags are accessed by the program.
+// Parse errors are handled according to SetErrorHandling.
 func Parse() {
-	// Parse ignores errors to maintain stdlib compatibility
-	_ = CommandLine.Parse(os.Args[1:])
+	err := CommandLine.Parse(os.Args[1:])
 
 	// Sync all registered pointers after parsing
 	syncPointers()
 	parsed = true
+
+	if err != nil {
+		handleParseError(err)
+	}
+}
+
+// handleParseError applies the error handling mode to a parse error, exiting with the
+// standard library codes: 0 for help and version requests, 2 for anything else
+func handleParseError(err error) {
+	switch errorHandling {
+	case ContinueOnError:
+		return
+	case PanicOnError:
+		panic(err)
+	}
+
+	// Help and version output has already been printed by Parse
+	if errors.Is(err, flashflags.ErrHelp) || errors.Is(err, flashflags.ErrVersion) {
+		exitFunc(0)
+		return
+	}
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	Usage()
+	exitFunc(2)
 }
 
 // Parsed reports whether the command-line flags have been parsed.
@@ -121,12 +185,7 @@ func syncPointers() {
 		}
 	}
 	for name, ptr := range uint64Vars {
-		intVal := CommandLine.GetInt(name)
-		if intVal < 0 {
-			*ptr = 0
-		} else {
-			*ptr = uint64(intVal)
-		}
+		*ptr = CommandLine.GetUint64(name)
 	}
 }
 
@@ -281,34 +340,14 @@ func UintVar(p *uint, name string, value uint, usage string) {
 // Uint64 defines a uint64 flag with specified name, default value, and usage string.
 // The return value is the address of a uint64 variable that stores the value of the flag.
 func Uint64(name string, value uint64, usage string) *uint64 {
-	intValue := 0
-	if value <= math.MaxInt {
-		intValue = int(value)
-	} else {
-		intValue = math.MaxInt
-	}
-	CommandLine.Int(name, intValue, usage)
-	uint64Ptr := new(uint64)
-	*uint64Ptr = value
-
-	pointerMutex.Lock()
-	uint64Vars[name] = uint64Ptr
-	pointerMutex.Unlock()
-
-	return uint64Ptr
+	return CommandLine.Uint64(name, value, usage)
 }
 
 // Uint64Var defines a uint64 flag with specified name, default value, and usage string.
 // The argument p points to a uint64 variable in which to store the value of the flag.
 func Uint64Var(p *uint64, name string, value uint64, usage string) {
 	*p = value
-	intValue := 0
-	if value <= math.MaxInt {
-		intValue = int(value)
-	} else {
-		intValue = math.MaxInt
-	}
-	CommandLine.Int(name, intValue, usage)
+	CommandLine.Uint64(name, value, usage)
 
 	pointerMutex.Lock()
 	uint64Vars[name] = p
@@ -345,10 +384,7 @@ func NFlag() int {
 
 // Set sets the value of the named command-line flag.
 func Set(name, value string) error {
-	// Flash-flags doesn't expose setFlagValue publicly
-	// We simulate it by parsing a fake argument
-	args := []string{"--" + name, value}
-	return CommandLine.Parse(args)
+	return CommandLine.Set(name, value)
 }
 
 // PrintDefaults prints, to standard error unless configured otherwise,
@@ -360,13 +396,14 @@ func PrintDefaults() {
 // Visit visits the command-line flags in lexicographical order, calling fn for each.
 // It visits only those flags that have been set.
 func Visit(fn func(*Flag)) {
-	CommandLine.VisitAll(func(f *flashflags.Flag) {
+	visitSorted(func(f *flashflags.Flag) {
 		if f.Changed() {
 			fn(&Flag{
 				Name:     f.Name(),
 				Usage:    f.Usage(),
 				Value:    &stringValue{f.Value()},
 				DefValue: "",
+				original: f,
 			})
 		}
 	})
@@ -375,7 +412,7 @@ func Visit(fn func(*Flag)) {
 // VisitAll visits the command-line flags in lexicographical order, calling fn
 // for each. It visits all flags, even those not set.
 func VisitAll(fn func(*Flag)) {
-	CommandLine.VisitAll(func(f *flashflags.Flag) {
+	visitSorted(func(f *flashflags.Flag) {
 		fn(&Flag{
 			Name:     f.Name(),
 			Usage:    f.Usage(),
@@ -386,6 +423,18 @@ func VisitAll(fn func(*Flag)) {
 	})
 }
 
+// visitSorted calls fn for each command-line flag in lexicographical order
+func visitSorted(fn func(*flashflags.Flag)) {
+	var flags []*flashflags.Flag
+	CommandLine.VisitAll(func(f *flashflags.Flag) {
+		flags = append(flags, f)
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name() < flags[j].Name() })
+	for _, f := range flags {
+		fn(f)
+	}
+}
+
 // Lookup returns the Flag structure of the named command-line flag,
 // returning nil if none exists.
 func Lookup(name string) *Flag {
@@ -421,6 +470,13 @@ func (f *Flag) Changed() bool {
 	return false
 }
 
+// Original returns the underlying flash-flags Flag, giving access to advanced
+// features (validators, groups, ...) while using the stdlib-compatible API.
+// Returns nil for flags not obtained from Lookup or VisitAll.
+func (f *Flag) Original() *flashflags.Flag {
+	return f.original
+}
+
 // Value is the interface to the dynamic value stored in a flag.
 // (The default value is represented as a string.)
 type Value interface {
@@ -437,7 +493,7 @@ func (s *stringValue) String() string {
 	if s.val == nil {
 		return ""
 	}
-	return s.val.(string)
+	return fmt.Sprint(s.val)
 }
 
 func (s *stringValue) Set(val string) error {
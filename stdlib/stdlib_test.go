@@ -1,7 +1,11 @@
 package stdlib_test
 
 import (
+	"errors"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"testing"
 
 	flag "github.com/agilira/flash-flags/stdlib"
@@ -80,3 +84,183 @@ func TestStdlibUtilityFunctions(t *testing.T) {
 		t.Error("Args should return empty slice, not nil")
 	}
 }
+
+func TestSingleDashLongFlags(t *testing.T) {
+	verbose := flag.Bool("sdverbose", false, "Verbose flag")
+	port := flag.Int("sdport", 8080, "Port flag")
+	name := flag.String("sdname", "", "Name flag")
+
+	oldArgs := os.Args
+	os.Args = []string{"test", "-sdverbose", "-sdport", "9090", "-sdname=shim", "rest"}
+	defer func() { os.Args = oldArgs }()
+
+	flag.Parse()
+
+	if !*verbose {
+		t.Errorf("Expected sdverbose true, got %v", *verbose)
+	}
+	if *port != 9090 {
+		t.Errorf("Expected sdport 9090, got %d", *port)
+	}
+	if *name != "shim" {
+		t.Errorf("Expected sdname 'shim', got '%s'", *name)
+	}
+	if flag.Arg(0) != "rest" {
+		t.Errorf("Expected remaining arg 'rest', got '%s'", flag.Arg(0))
+	}
+}
+
+func TestSetProgramName(t *testing.T) {
+	oldName := flag.CommandLine.Name()
+	defer flag.SetProgramName(oldName)
+
+	flag.SetProgramName("mytool")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	flag.PrintDefaults()
+	flag.Usage()
+	os.Stdout = oldStdout
+	_ = w.Close()
+
+	out, _ := io.ReadAll(r)
+	if !strings.Contains(string(out), "Usage of mytool:") {
+		t.Errorf("Expected PrintDefaults to use the program name, got %q", out)
+	}
+	if !strings.Contains(string(out), "Usage: mytool [options]") {
+		t.Errorf("Expected Usage to use the program name, got %q", out)
+	}
+}
+
+func TestFlagOriginal(t *testing.T) {
+	flag.Int("origport", 8080, "Port with validator")
+
+	f := flag.Lookup("origport")
+	if f == nil || f.Original() == nil {
+		t.Fatal("Expected Lookup to expose the original flag")
+	}
+	if f.Original().Type() != "int" {
+		t.Errorf("Expected original type int, got %s", f.Original().Type())
+	}
+
+	f.Original().SetValidator(func(v interface{}) error {
+		if v.(int) < 1024 {
+			return errors.New("port must be >= 1024")
+		}
+		return nil
+	})
+
+	err := flag.CommandLine.Parse([]string{"-origport", "80"})
+	if err == nil || !strings.Contains(err.Error(), "port must be >= 1024") {
+		t.Errorf("Expected validator error, got %v", err)
+	}
+	if err := flag.CommandLine.Parse([]string{"-origport", "8443"}); err != nil {
+		t.Errorf("Expected valid port to pass, got %v", err)
+	}
+
+	if (&flag.Flag{}).Original() != nil {
+		t.Error("Expected nil original for a zero Flag")
+	}
+}
+
+func TestVisitNonStringFlags(t *testing.T) {
+	flag.Int("visitport", 8080, "Port")
+	flag.Bool("visitdebug", false, "Debug")
+	flag.String("visitname", "default", "Name")
+
+	if err := flag.CommandLine.Parse([]string{"-visitport", "9090", "-visitdebug"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var visited []string
+	flag.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "visit") {
+			visited = append(visited, f.Name+"="+f.Value.String())
+		}
+	})
+
+	expected := "visitdebug=true,visitport=9090"
+	if got := strings.Join(visited, ","); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestUint64Max(t *testing.T) {
+	big := flag.Uint64("bigcount", math.MaxUint64, "Large counter")
+	var bigVar uint64
+	flag.Uint64Var(&bigVar, "bigvar", 0, "Large counter var")
+
+	oldArgs := os.Args
+	os.Args = []string{"test", "-bigvar", "18446744073709551615"}
+	defer func() { os.Args = oldArgs }()
+
+	flag.Parse()
+
+	if *big != math.MaxUint64 {
+		t.Errorf("Expected default %d, got %d", uint64(math.MaxUint64), *big)
+	}
+	if bigVar != math.MaxUint64 {
+		t.Errorf("Expected parsed %d, got %d", uint64(math.MaxUint64), bigVar)
+	}
+}
+
+func TestParseExitCodes(t *testing.T) {
+	flag.Int("exitport", 8080, "Port for exit code tests")
+	defer flag.SetErrorHandling(flag.ExitOnError)
+
+	// parseWithArgs runs Parse with the given arguments, discarding output and
+	// returning the exit code (-1 if Parse didn't exit)
+	parseWithArgs := func(args ...string) int {
+		code := -1
+		restore := flag.SetExitFunc(func(c int) { code = c })
+		defer restore()
+
+		oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+		devNull, err := os.Open(os.DevNull)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", os.DevNull, err)
+		}
+		os.Args = append([]string{"test"}, args...)
+		os.Stdout, os.Stderr = devNull, devNull
+		defer func() {
+			os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr
+			_ = devNull.Close()
+		}()
+
+		flag.Parse()
+		return code
+	}
+
+	flag.SetErrorHandling(flag.ExitOnError)
+	if code := parseWithArgs("-exitport", "9090"); code != -1 {
+		t.Errorf("Expected no exit on success, got %d", code)
+	}
+	if code := parseWithArgs("-h"); code != 0 {
+		t.Errorf("Expected exit 0 for -h, got %d", code)
+	}
+	if code := parseWithArgs("-exitport", "abc"); code != 2 {
+		t.Errorf("Expected exit 2 for an invalid value, got %d", code)
+	}
+	if code := parseWithArgs("-no-such-exit-flag"); code != 2 {
+		t.Errorf("Expected exit 2 for an unknown flag, got %d", code)
+	}
+
+	flag.SetErrorHandling(flag.ContinueOnError)
+	if code := parseWithArgs("-exitport", "abc"); code != -1 {
+		t.Errorf("Expected no exit with ContinueOnError, got %d", code)
+	}
+
+	flag.SetErrorHandling(flag.PanicOnError)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic with PanicOnError")
+			}
+		}()
+		parseWithArgs("-exitport", "abc")
+	}()
+}